@@ -1,6 +1,7 @@
 package aliens
 
 import (
+	"fmt"
 	"strconv"
 )
 
@@ -17,3 +18,20 @@ func createAliens(numAliens int) []alien {
 	}
 	return aliens
 }
+
+// namedAliens creates aliens with the given names which must be unique
+func namedAliens(names []string) ([]alien, error) {
+	aliens := make([]alien, len(names))
+	unique := make(map[string]bool, len(names))
+	for i, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("alien %d has no name", i)
+		}
+		if unique[name] {
+			return nil, fmt.Errorf("more than one alien is named %s", name)
+		}
+		unique[name] = true
+		aliens[i] = alien(name)
+	}
+	return aliens, nil
+}
@@ -0,0 +1,269 @@
+package aliens
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// MapAnalysis describes the structure of a city map before any invasion.  All
+// lists of cities are in city name sorted order.
+type MapAnalysis struct {
+	Cities int `json:"cities"`
+	Roads  int `json:"roads"` // one way roads, so a two way road counts twice
+
+	// Components are groups of cities connected by roads in either direction,
+	// largest group first
+	Components [][]string `json:"components"`
+
+	// DeadEnds are cities without any roads out, aliens that land here are
+	// trapped for the duration of the invasion
+	DeadEnds []string `json:"deadEnds"`
+
+	// Oscillations are pairs of cities whose only roads out lead to each other
+	// where aliens can pass each other until the end of the invasion
+	Oscillations [][2]string `json:"oscillations"`
+
+	// Diameter is the most roads an alien has to travel going the shortest way
+	// between any two cities that are reachable
+	Diameter int `json:"diameter"`
+
+	// Degrees counts cities by the number of roads out of the city
+	Degrees map[int]int `json:"degrees"`
+
+	// Articulations are cities whose destruction would disconnect their
+	// component
+	Articulations []string `json:"articulations"`
+}
+
+// Analyze the structure of the map
+func (m *Map) Analyze() *MapAnalysis {
+	return analyze(m.cities)
+}
+
+func analyze(cities map[string]*city) *MapAnalysis {
+	names, out, neighbors := roadIndex(cities)
+	a := &MapAnalysis{
+		Cities:  len(names),
+		Degrees: make(map[int]int),
+	}
+	for i, name := range names {
+		a.Roads += len(out[i])
+		a.Degrees[len(out[i])]++
+		if len(out[i]) == 0 {
+			a.DeadEnds = append(a.DeadEnds, name)
+		}
+	}
+	for i, name := range names {
+		if j, only := onlyNeighbor(out[i]); only && i < j {
+			if back, only := onlyNeighbor(out[j]); only && back == i {
+				a.Oscillations = append(a.Oscillations, [2]string{name, names[j]})
+			}
+		}
+	}
+	a.Components = components(names, neighbors)
+	a.Diameter = diameter(out)
+	a.Articulations = articulations(names, neighbors)
+	return a
+}
+
+// onlyNeighbor is the city every road out leads to when there is just one,
+// there can be more than one road to it in different directions
+func onlyNeighbor(out []int) (int, bool) {
+	if len(out) == 0 {
+		return 0, false
+	}
+	for _, j := range out[1:] {
+		if j != out[0] {
+			return 0, false
+		}
+	}
+	return out[0], true
+}
+
+// roadIndex numbers cities in city name sorted order and lists the roads out of
+// each city and the roads in either direction using those numbers
+func roadIndex(cities map[string]*city) (names []string, out [][]int, neighbors [][]int) {
+	names = cityNames(cities)
+	index := make(map[*city]int, len(names))
+	for i, name := range names {
+		index[cities[name]] = i
+	}
+	out = make([][]int, len(names))
+	undirected := make([]map[int]bool, len(names))
+	for i := range names {
+		undirected[i] = make(map[int]bool)
+	}
+	for i, name := range names {
+		for direction := range directions {
+			neighbor := cities[name].neighoringCity(direction)
+			if neighbor == nil {
+				continue
+			}
+			j, exists := index[neighbor]
+			if !exists {
+				// roads to cities that are not part of given set
+				continue
+			}
+			out[i] = append(out[i], j)
+			undirected[i][j] = true
+			undirected[j][i] = true
+		}
+	}
+	neighbors = make([][]int, len(names))
+	for i := range names {
+		for j := range undirected[i] {
+			neighbors[i] = append(neighbors[i], j)
+		}
+		sort.Ints(neighbors[i])
+	}
+	return names, out, neighbors
+}
+
+// components groups cities that are connected, largest group first and
+// otherwise by first city name
+func components(names []string, neighbors [][]int) [][]string {
+	var groups [][]string
+	visited := make([]bool, len(names))
+	for start := range names {
+		if visited[start] {
+			continue
+		}
+		var group []string
+		pending := []int{start}
+		visited[start] = true
+		for len(pending) > 0 {
+			i := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			group = append(group, names[i])
+			for _, j := range neighbors[i] {
+				if !visited[j] {
+					visited[j] = true
+					pending = append(pending, j)
+				}
+			}
+		}
+		sort.Strings(group)
+		groups = append(groups, group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i]) > len(groups[j])
+	})
+	return groups
+}
+
+// diameter is longest shortest path by breadth first search from every city
+// following roads in the direction they go
+func diameter(out [][]int) int {
+	longest := 0
+	distance := make([]int, len(out))
+	for start := range out {
+		for i := range distance {
+			distance[i] = -1
+		}
+		distance[start] = 0
+		pending := []int{start}
+		for len(pending) > 0 {
+			i := pending[0]
+			pending = pending[1:]
+			for _, j := range out[i] {
+				if distance[j] < 0 {
+					distance[j] = distance[i] + 1
+					if distance[j] > longest {
+						longest = distance[j]
+					}
+					pending = append(pending, j)
+				}
+			}
+		}
+	}
+	return longest
+}
+
+// articulations uses Tarjan's algorithm treating every road as two way
+func articulations(names []string, neighbors [][]int) []string {
+	discovered := make([]int, len(names))
+	low := make([]int, len(names))
+	isArticulation := make([]bool, len(names))
+	clock := 0
+	var visit func(i, parent int)
+	visit = func(i, parent int) {
+		clock++
+		discovered[i] = clock
+		low[i] = clock
+		children := 0
+		for _, j := range neighbors[i] {
+			if discovered[j] == 0 {
+				children++
+				visit(j, i)
+				if low[j] < low[i] {
+					low[i] = low[j]
+				}
+				if parent >= 0 && low[j] >= discovered[i] {
+					isArticulation[i] = true
+				}
+			} else if j != parent && discovered[j] < low[i] {
+				low[i] = discovered[j]
+			}
+		}
+		if parent < 0 && children > 1 {
+			isArticulation[i] = true
+		}
+	}
+	var found []string
+	for i := range names {
+		if discovered[i] == 0 {
+			visit(i, -1)
+		}
+	}
+	for i, name := range names {
+		if isArticulation[i] {
+			found = append(found, name)
+		}
+	}
+	return found
+}
+
+// Write analysis as human readable report
+func (a *MapAnalysis) Write(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cities: %d\n", a.Cities)
+	fmt.Fprintf(&b, "roads: %d\n", a.Roads)
+	fmt.Fprintf(&b, "components: %d\n", len(a.Components))
+	for _, group := range a.Components {
+		fmt.Fprintf(&b, "  %s\n", strings.Join(group, " "))
+	}
+	fmt.Fprintf(&b, "dead ends: %d\n", len(a.DeadEnds))
+	for _, name := range a.DeadEnds {
+		fmt.Fprintf(&b, "  %s\n", name)
+	}
+	fmt.Fprintf(&b, "oscillations: %d\n", len(a.Oscillations))
+	for _, pair := range a.Oscillations {
+		fmt.Fprintf(&b, "  %s %s\n", pair[0], pair[1])
+	}
+	fmt.Fprintf(&b, "diameter: %d\n", a.Diameter)
+	degrees := make([]int, 0, len(a.Degrees))
+	for degree := range a.Degrees {
+		degrees = append(degrees, degree)
+	}
+	sort.Ints(degrees)
+	fmt.Fprintf(&b, "degrees:\n")
+	for _, degree := range degrees {
+		fmt.Fprintf(&b, "  %d road(s) out: %d cities\n", degree, a.Degrees[degree])
+	}
+	fmt.Fprintf(&b, "articulations: %d\n", len(a.Articulations))
+	for _, name := range a.Articulations {
+		fmt.Fprintf(&b, "  %s\n", name)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes analysis as JSON document
+func (a *MapAnalysis) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a)
+}
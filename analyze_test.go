@@ -0,0 +1,70 @@
+package aliens
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	in := `
+Boston north=Bangor south=NewYork
+NewYork west=Albany
+Portland east=Salem
+Albany
+Moon
+`
+	cities, err := parse(strings.NewReader(in), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := analyze(cities)
+	assert.Equal(t, 7, a.Cities)
+	assert.Equal(t, 4, a.Roads)
+	assert.Equal(t, [][]string{
+		{"Albany", "Bangor", "Boston", "NewYork"},
+		{"Portland", "Salem"},
+		{"Moon"},
+	}, a.Components)
+	assert.Equal(t, []string{"Albany", "Bangor", "Moon", "Salem"}, a.DeadEnds)
+	assert.Nil(t, a.Oscillations)
+	assert.Equal(t, 2, a.Diameter)
+	assert.Equal(t, map[int]int{0: 4, 1: 2, 2: 1}, a.Degrees)
+	assert.Equal(t, []string{"Boston", "NewYork"}, a.Articulations)
+}
+
+func TestAnalyzeOscillations(t *testing.T) {
+	in := `
+Boston north=Bangor
+Portland east=Salem
+Salem south=Albany
+Denver north=Reno east=Reno
+`
+	cities, err := parse(strings.NewReader(in), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := analyze(cities)
+	// two roads to the same city still leave only one city to go to
+	assert.Equal(t, [][2]string{{"Bangor", "Boston"}, {"Denver", "Reno"}}, a.Oscillations)
+	assert.Nil(t, a.DeadEnds)
+	assert.Equal(t, []string{"Salem"}, a.Articulations)
+}
+
+func TestAnalyzeReport(t *testing.T) {
+	in, err := os.Open("testdata/small-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	m, err := ParseMap(in, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, m.Analyze().Write(&buf))
+	Golden(t, *updateFlag, "testdata/small-map-analysis.golden", &buf)
+}
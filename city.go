@@ -11,6 +11,8 @@ const (
 	South
 	East
 	West
+	Up
+	Down
 )
 
 func oppositeDirection(d int) int {
@@ -23,18 +25,36 @@ func oppositeDirection(d int) int {
 		return West
 	case West:
 		return East
+	case Up:
+		return Down
+	case Down:
+		return Up
 	}
 	panic(fmt.Sprintf("bad direction %d", d))
 }
 
 // convient list of directions in order of constants.
 var directions = []int{
-	North, South, East, West,
+	North, South, East, West, Up, Down,
 }
 
 // used when encoding and decoding maps
 var directionLabels = []string{
-	"north", "south", "east", "west",
+	"north", "south", "east", "west", "up", "down",
+}
+
+// compassLabels are the directions maps have unless they declare others
+var compassLabels = directionLabels[:West+1]
+
+// directionFromLabel is opposite of directionLabels or -1 if label is not a
+// direction
+func directionFromLabel(label string) int {
+	for direction, candidate := range directionLabels {
+		if candidate == label {
+			return direction
+		}
+	}
+	return -1
 }
 
 type city struct {
@@ -43,6 +63,21 @@ type city struct {
 	South *city
 	East  *city
 	West  *city
+	Up    *city
+	Down  *city
+}
+
+// compassOnly is true when no city has roads in directions other than compass
+// directions
+func compassOnly(cities map[string]*city) bool {
+	for _, c := range cities {
+		for direction := len(compassLabels); direction < len(directions); direction++ {
+			if c.neighoringCity(direction) != nil {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 // cityNames are in city name sorted order
@@ -87,6 +122,10 @@ func (c *city) addNeighbor(direction int, neighbor *city) error {
 		c.West = neighbor
 	case East:
 		c.East = neighbor
+	case Up:
+		c.Up = neighbor
+	case Down:
+		c.Down = neighbor
 	default:
 		return fmt.Errorf("invalid direction %d", direction)
 	}
@@ -118,35 +157,90 @@ func (c *city) neighoringCity(direction int) *city {
 		return c.West
 	case East:
 		return c.East
+	case Up:
+		return c.Up
+	case Down:
+		return c.Down
 	default:
 		panic(fmt.Errorf("invalid direction %d", direction))
 	}
 }
 
+// roadLabels are neighboring cities by direction label
+func (c *city) roadLabels() map[string]string {
+	roads := make(map[string]string)
+	for direction, label := range directionLabels {
+		if neighbor := c.neighoringCity(direction); neighbor != nil {
+			roads[label] = neighbor.Name
+		}
+	}
+	return roads
+}
+
+// roadsOut counts neighboring cities
+func (c *city) roadsOut() int {
+	n := 0
+	for direction := range directions {
+		if c.neighoringCity(direction) != nil {
+			n++
+		}
+	}
+	return n
+}
+
 // destroy will trap any aliens in the city and remove all roads
-// into city from neighboring cities
-func (c *city) destroy(a, b alien) {
+// into city from any city on the map. returns the roads that were removed
+func (c *city) destroy(cities map[string]*city, a, b alien) []Road {
+	var severed []Road
 	for direction := range directions {
 		neighbor := c.neighoringCity(direction)
 		if neighbor != nil {
+			c.removeNeighbor(direction)
+			severed = append(severed, Road{From: c.Name, Direction: directionLabels[direction], To: neighbor.Name})
+
 			// remove all pointers back to destroyed city from neighboring cities
-			// where pointer is defined by reference in the opposite direction
-			switch direction {
-			case North:
-				c.North = nil
-				neighbor.South = nil
-			case South:
-				c.South = nil
-				neighbor.North = nil
-			case East:
-				c.East = nil
-				neighbor.West = nil
-			case West:
-				c.West = nil
-				neighbor.East = nil
+			// where pointer is defined by reference in the opposite direction.
+			// strict maps might not have a road back or it might go elsewhere
+			back := oppositeDirection(direction)
+			if neighbor.neighoringCity(back) == c {
+				neighbor.removeNeighbor(back)
+				severed = append(severed, Road{From: neighbor.Name, Direction: directionLabels[back], To: c.Name})
 			}
 		}
 	}
+
+	// strict maps can also have one-way roads into city from cities that
+	// are not its neighbors or that reach it from another direction
+	for _, name := range cityNames(cities) {
+		other := cities[name]
+		for direction := range directions {
+			if other.neighoringCity(direction) == c {
+				other.removeNeighbor(direction)
+				severed = append(severed, Road{From: other.Name, Direction: directionLabels[direction], To: c.Name})
+			}
+		}
+	}
+	return severed
+}
+
+// removeNeighbor removes road in given direction, if there is one
+func (c *city) removeNeighbor(direction int) {
+	switch direction {
+	case North:
+		c.North = nil
+	case South:
+		c.South = nil
+	case West:
+		c.West = nil
+	case East:
+		c.East = nil
+	case Up:
+		c.Up = nil
+	case Down:
+		c.Down = nil
+	default:
+		panic(fmt.Errorf("invalid direction %d", direction))
+	}
 }
 
 // cityRef is a temporary struct used as a holding place to ultimately
@@ -157,6 +251,11 @@ type cityRef struct {
 	South string
 	East  string
 	West  string
+	Up    string
+	Down  string
+
+	strict bool   // map city is from is strict
+	source string // file and line city is from, empty if map is not a file
 }
 
 func (c cityRef) neighoringCity(direction int) string {
@@ -169,7 +268,21 @@ func (c cityRef) neighoringCity(direction int) string {
 		return c.West
 	case East:
 		return c.East
+	case Up:
+		return c.Up
+	case Down:
+		return c.Down
 	default:
 		panic(fmt.Errorf("invalid direction %d", direction))
 	}
 }
+
+// checkDirections makes sure city only has roads in declared directions
+func (c cityRef) checkDirections(declared []string) error {
+	for direction, label := range directionLabels {
+		if c.neighoringCity(direction) != "" && !containsLabel(declared, label) {
+			return fmt.Errorf("parse error, '%s' is not a recognized direction, it can be declared with #!directions", label)
+		}
+	}
+	return nil
+}
@@ -41,7 +41,7 @@ func TestCity(t *testing.T) {
 	})
 
 	t.Run("destroy", func(t *testing.T) {
-		x.destroy(alien("a"), alien("b"))
+		x.destroy(map[string]*city{"x": x, "n": n, "s": s, "e": e, "w": w}, alien("a"), alien("b"))
 		assert.Nil(t, x.South)
 		assert.Nil(t, s.North)
 
@@ -65,3 +65,27 @@ func TestAddNeighborBidirectional(t *testing.T) {
 	err = b.addNeighborBidiectional(South, c)
 	assert.Error(t, err)
 }
+
+func TestDestroySevered(t *testing.T) {
+	// strict maps can have roads that do not lead back
+	a := &city{Name: "a"}
+	b := &city{Name: "b"}
+	c := &city{Name: "c"}
+	assert.NoError(t, a.addNeighbor(North, b))
+	assert.NoError(t, b.addNeighbor(South, c))
+	assert.NoError(t, a.addNeighbor(East, c))
+	assert.NoError(t, c.addNeighbor(West, a))
+	// one-way road into a from a city that is not its neighbor
+	d := &city{Name: "d"}
+	assert.NoError(t, d.addNeighbor(North, a))
+	cities := map[string]*city{"a": a, "b": b, "c": c, "d": d}
+	severed := a.destroy(cities, alien("1"), alien("2"))
+	assert.Equal(t, []Road{
+		{From: "a", Direction: "north", To: "b"},
+		{From: "a", Direction: "east", To: "c"},
+		{From: "c", Direction: "west", To: "a"},
+		{From: "d", Direction: "north", To: "a"},
+	}, severed)
+	assert.Equal(t, c, b.South)
+	assert.Nil(t, d.North)
+}
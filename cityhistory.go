@@ -0,0 +1,159 @@
+package aliens
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// CityHistory is every alien that came to a city, when and by whom it was
+// destroyed and roads out of it that went with destroyed neighbors
+type CityHistory struct {
+	City   string  `json:"city"`
+	Visits []Visit `json:"visits"`
+
+	// nil unless city was destroyed
+	Destroyed *Destruction `json:"destroyed,omitempty"`
+
+	// roads out of city removed when the neighbor they lead to was destroyed
+	Severed []SeveredRoad `json:"severed"`
+}
+
+// Visit is an alien arriving in a city in a round, round zero is landing
+type Visit struct {
+	Round int    `json:"round"`
+	Alien string `json:"alien"`
+}
+
+// Destruction is round a city was destroyed and the two aliens that did it,
+// alien that arrived last is first
+type Destruction struct {
+	Round  int       `json:"round"`
+	Aliens [2]string `json:"aliens"`
+}
+
+// SeveredRoad is a road out of city that was removed in a round
+type SeveredRoad struct {
+	Round     int    `json:"round"`
+	Direction string `json:"direction"`
+	To        string `json:"to"`
+}
+
+// CityHistories of all cities in order of city name
+type CityHistories []CityHistory
+
+// trackCityHistory starts a history for every city on the map
+func (sim *Invasion) trackCityHistory() {
+	sim.cityHistories = make(map[string]*CityHistory, len(sim.cities))
+	for name := range sim.cities {
+		sim.cityHistories[name] = &CityHistory{City: name}
+	}
+}
+
+// visit, destruction and sever are harmless to call when city history is
+// not tracked
+func (sim *Invasion) visit(a alien, c *city) {
+	if h, tracked := sim.cityHistories[c.Name]; tracked {
+		h.Visits = append(h.Visits, Visit{Round: sim.roundsCompleted, Alien: string(a)})
+	}
+}
+
+func (sim *Invasion) destruction(fallen FallenCity) {
+	if h, tracked := sim.cityHistories[fallen.City]; tracked {
+		h.Destroyed = &Destruction{Round: sim.roundsCompleted, Aliens: fallen.Aliens}
+	}
+}
+
+// sever records roads removed by destroying a city on the cities the roads
+// lead out of. Roads out of the destroyed city itself are not recorded.
+func (sim *Invasion) sever(destroyed *city, roads []Road) {
+	for _, road := range roads {
+		if road.From == destroyed.Name {
+			continue
+		}
+		if h, tracked := sim.cityHistories[road.From]; tracked {
+			h.Severed = append(h.Severed, SeveredRoad{Round: sim.roundsCompleted, Direction: road.Direction, To: road.To})
+		}
+	}
+}
+
+// cityHistoryList is nil when city history is not tracked
+func (sim *Invasion) cityHistoryList() CityHistories {
+	if sim.cityHistories == nil {
+		return nil
+	}
+	list := make(CityHistories, 0, len(sim.cityHistories))
+	for _, h := range sim.cityHistories {
+		copied := *h
+		copied.Visits = append([]Visit(nil), h.Visits...)
+		copied.Severed = append([]SeveredRoad(nil), h.Severed...)
+		if h.Destroyed != nil {
+			destroyed := *h.Destroyed
+			copied.Destroyed = &destroyed
+		}
+		list = append(list, copied)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].City < list[j].City
+	})
+	return list
+}
+
+// WriteCSV writes a row for every event in a city's history by round with
+// columns city, round, event, alien, otherAlien and road. Event is visit,
+// destroyed or severed. Destroyed rows have both aliens and
+// severed rows have the road like north=Boston. A row with only the city is
+// written for cities nothing happened to.
+func (histories CityHistories) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"city", "round", "event", "alien", "otherAlien", "road"}); err != nil {
+		return err
+	}
+	type event struct {
+		round int
+		rank  int // order of events in the same round
+		row   []string
+	}
+	for _, h := range histories {
+		var events []event
+		for _, v := range h.Visits {
+			events = append(events, event{v.Round, 0, []string{h.City, strconv.Itoa(v.Round), "visit", v.Alien, "", ""}})
+		}
+		if d := h.Destroyed; d != nil {
+			events = append(events, event{d.Round, 2, []string{h.City, strconv.Itoa(d.Round), "destroyed", d.Aliens[0], d.Aliens[1], ""}})
+		}
+		for _, s := range h.Severed {
+			events = append(events, event{s.Round, 1, []string{h.City, strconv.Itoa(s.Round), "severed", "", "", s.Direction + "=" + s.To}})
+		}
+		if len(events) == 0 {
+			if err := out.Write([]string{h.City, "", "", "", "", ""}); err != nil {
+				return err
+			}
+			continue
+		}
+		// roads can only be severed while city stands so destruction is last
+		// in its round
+		sort.SliceStable(events, func(i, j int) bool {
+			if events[i].round != events[j].round {
+				return events[i].round < events[j].round
+			}
+			return events[i].rank < events[j].rank
+		})
+		for _, e := range events {
+			if err := out.Write(e.row); err != nil {
+				return err
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON writes city histories as JSON document
+func (histories CityHistories) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(histories)
+}
@@ -0,0 +1,44 @@
+package aliens
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCityHistories(t *testing.T) {
+	in, err := os.Open("testdata/small-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	result, err := Invade(Options{
+		Seed:             20,
+		NumberAliens:     10,
+		InvasionRounds:   3,
+		CityMapInput:     in,
+		TrackCityHistory: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(result.CityHistories))
+	destroyed := 0
+	for _, h := range result.CityHistories {
+		if h.Destroyed != nil {
+			destroyed++
+		}
+	}
+	assert.Equal(t, len(result.Fallen), destroyed)
+
+	var buf bytes.Buffer
+	assert.NoError(t, result.CityHistories.WriteCSV(&buf))
+	Golden(t, *updateFlag, "testdata/city-histories.csv", &buf)
+	assert.NoError(t, result.CityHistories.WriteJSON(&buf))
+	Golden(t, *updateFlag, "testdata/city-histories.json", &buf)
+
+	// not tracked unless asked for
+	result, err = Invade(Options{NumberAliens: 10, CityMap: &Map{cities: generateCityMap(2)}})
+	assert.NoError(t, err)
+	assert.Nil(t, result.CityHistories)
+}
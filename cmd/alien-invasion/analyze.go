@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dhubler/aliens"
+)
+
+var analyzeCommand = &command{
+	name:    "analyze",
+	args:    "[city-map-file]",
+	summary: "Report on the structure of a city map",
+	run:     analyze,
+}
+
+func analyze(c *cli, flags *flag.FlagSet, args []string) error {
+	strict := flags.Bool("strict", false, "Use a more strict parse that does not back link any cities in opposite directions")
+	format := flags.String("format", "text", "Output format: text or json")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	var write func(a *aliens.MapAnalysis, w io.Writer) error
+	switch *format {
+	case "text":
+		write = (*aliens.MapAnalysis).Write
+	case "json":
+		write = (*aliens.MapAnalysis).WriteJSON
+	default:
+		fmt.Fprintf(c.stderr, "unrecognized format '%s'\n", *format)
+		flags.Usage()
+		return errUsage
+	}
+	m, err := c.readMap(flags.Arg(0), *strict)
+	if err != nil {
+		return err
+	}
+	return write(m.Analyze(), c.stdout)
+}
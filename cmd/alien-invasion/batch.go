@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dhubler/aliens"
+)
+
+var batchCommand = &command{
+	name:    "batch",
+	args:    "[city-map-file]",
+	summary: "Run many invasions on the same map and report statistics",
+	run:     batch,
+}
+
+func batch(c *cli, flags *flag.FlagSet, args []string) error {
+	runs := flags.Int("runs", 10, "Number of invasions to run")
+	numAliens := flags.Int("numAliens", aliens.DefaultNumberAliens, "Number of aliens invading")
+	numRounds := flags.Int("numRounds", aliens.DefaultInvasionRounds, "Limit the number of rounds the aliens perform before giving up or -1 to go until no more cities can be destroyed")
+	maxRounds := flags.Int("maxRounds", 0, "Most rounds any invasion can run or -1 for no limit. Default of zero for 10000")
+	seed := flags.Int64("seed", 0, "Random seed of first invasion, each invasion after that adds one to seed.  Default of zero for random each time")
+	strict := flags.Bool("strict", false, "Use a more strict parse that does not back link any cities in opposite directions")
+	outputFile := flags.String("outputFile", "", "Optional statistics output file")
+	parallel := flags.Int("parallel", runtime.NumCPU(), "Number of invasions to run at the same time")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	if *runs < 1 {
+		return fmt.Errorf("runs must be at least 1, got %d", *runs)
+	}
+	if *parallel < 1 {
+		return fmt.Errorf("parallel must be at least 1, got %d", *parallel)
+	}
+	m, err := c.readMap(flags.Arg(0), *strict)
+	if err != nil {
+		return err
+	}
+	firstSeed := *seed
+	if firstSeed == 0 {
+		firstSeed = time.Now().UnixNano()
+	}
+	// invasions share nothing so they can run in any order, results are kept
+	// in seed order
+	results := make([]*aliens.Result, *runs)
+	errs := make([]error, *runs)
+	limit := make(chan struct{}, *parallel)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			results[i], errs[i] = aliens.Invade(aliens.Options{
+				NumberAliens:   *numAliens,
+				InvasionRounds: *numRounds,
+				MaxRounds:      *maxRounds,
+				Seed:           firstSeed + int64(i),
+				CityMap:        m,
+			})
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return c.writeFile(*outputFile, func(out io.Writer) error {
+		return writeBatch(out, results)
+	})
+}
+
+// writeBatch writes a table of statistics, one invasion per row and mean of
+// every column in last row
+func writeBatch(w io.Writer, results []*aliens.Result) error {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "seed\trounds\tcities left\tcities fallen\taliens killed\taliens trapped\taliens left\t")
+	var rounds, left, fallen, killed, trapped, roaming int
+	for _, r := range results {
+		fmt.Fprintf(table, "%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n", r.Seed, r.Rounds, len(r.Remaining), len(r.Fallen), r.AliensKilled(), r.AliensTrapped, r.AliensLeft)
+		rounds += r.Rounds
+		left += len(r.Remaining)
+		fallen += len(r.Fallen)
+		killed += r.AliensKilled()
+		trapped += r.AliensTrapped
+		roaming += r.AliensLeft
+	}
+	n := float64(len(results))
+	fmt.Fprintf(table, "mean\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t\n",
+		float64(rounds)/n, float64(left)/n, float64(fallen)/n, float64(killed)/n, float64(trapped)/n, float64(roaming)/n)
+	return table.Flush()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dhubler/aliens"
+)
+
+var convertCommand = &command{
+	name:    "convert",
+	args:    "[city-map-file]",
+	summary: "Convert a city map between file formats",
+	run:     convert,
+}
+
+// mapReader reads a city map in one format
+type mapReader func(r io.Reader, strict bool) (*aliens.Map, []aliens.SkippedRoad, error)
+
+// formats convert understands. Formats that do not require a direction for
+// every road give back roads they could not put on map.
+var mapReaders = map[string]mapReader{
+	"text":      completeReader(aliens.ParseMap),
+	"json":      completeReader(aliens.ReadMapJSON),
+	"csv":       aliens.ReadMapCSV,
+	"graphml":   aliens.ReadMapGraphML,
+	"adjacency": aliens.ReadMapAdjacencyJSON,
+}
+
+var mapWriters = map[string]func(m *aliens.Map, w io.Writer) error{
+	"text":      (*aliens.Map).Write,
+	"json":      (*aliens.Map).WriteJSON,
+	"csv":       (*aliens.Map).WriteCSV,
+	"graphml":   (*aliens.Map).WriteGraphML,
+	"adjacency": (*aliens.Map).WriteAdjacencyJSON,
+}
+
+const mapFormats = "text, json, csv, graphml or adjacency"
+
+// completeReader never skips roads, anything it cannot read is an error
+func completeReader(read func(r io.Reader, strict bool) (*aliens.Map, error)) mapReader {
+	return func(r io.Reader, strict bool) (*aliens.Map, []aliens.SkippedRoad, error) {
+		m, err := read(r, strict)
+		return m, nil, err
+	}
+}
+
+func convert(c *cli, flags *flag.FlagSet, args []string) error {
+	from := flags.String("from", "text", "Format of input city map, "+mapFormats)
+	to := flags.String("to", "json", "Format of output city map, "+mapFormats)
+	strict := flags.Bool("strict", false, "Use a more strict parse that does not back link any cities in opposite directions")
+	outputFile := flags.String("outputFile", "", "Optional city map output file")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	read, valid := mapReaders[*from]
+	if !valid {
+		return fmt.Errorf("unrecognized format '%s'", *from)
+	}
+	write, valid := mapWriters[*to]
+	if !valid {
+		return fmt.Errorf("unrecognized format '%s'", *to)
+	}
+	m, skipped, err := c.readMapFormat(flags.Arg(0), *strict, *from, read)
+	if err != nil {
+		return err
+	}
+	for _, road := range skipped {
+		fmt.Fprintf(c.stderr, "skipped road %s\n", road)
+	}
+	err = c.writeFile(*outputFile, func(out io.Writer) error {
+		return write(m, out)
+	})
+	if err == nil && len(skipped) > 0 {
+		// rest of map is still written but conversion was not complete
+		err = fmt.Errorf("%d road(s) could not be converted", len(skipped))
+	}
+	return err
+}
+
+// readMapFormat in given format, text maps in files can include other maps
+func (c *cli) readMapFormat(fname string, strict bool, format string, read mapReader) (*aliens.Map, []aliens.SkippedRoad, error) {
+	if format == "text" {
+		m, err := c.readMap(fname, strict)
+		return m, nil, err
+	}
+	in, err := c.open(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer in.Close()
+	return read(in, strict)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dhubler/aliens"
+)
+
+var diffCommand = &command{
+	name:    "diff",
+	args:    "old-city-map-file new-city-map-file",
+	summary: "Show cities and roads that differ between two city maps",
+	run:     diff,
+}
+
+// errDifferent is returned when maps differ, or are not formatted, so scripts
+// can tell from exit code. Differences are already written to stdout.
+var errDifferent = errors.New("maps differ")
+
+func diff(c *cli, flags *flag.FlagSet, args []string) error {
+	strict := flags.Bool("strict", false, "Use a more strict parse that does not back link any cities in opposite directions")
+	format := flags.String("format", "text", "Output format: text or json")
+	if err := c.parse(flags, args, 2); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(c.stderr, "two city map files are required, either may be - for stdin")
+		flags.Usage()
+		return errUsage
+	}
+	var write func(d *aliens.MapDiff, w io.Writer) error
+	switch *format {
+	case "text":
+		write = (*aliens.MapDiff).Write
+	case "json":
+		write = (*aliens.MapDiff).WriteJSON
+	default:
+		fmt.Fprintf(c.stderr, "unrecognized format '%s'\n", *format)
+		flags.Usage()
+		return errUsage
+	}
+	if flags.Arg(0) == "-" && flags.Arg(1) == "-" {
+		fmt.Fprintln(c.stderr, "only one city map can be read from stdin")
+		flags.Usage()
+		return errUsage
+	}
+	before, err := c.readMap(flags.Arg(0), *strict)
+	if err != nil {
+		return err
+	}
+	after, err := c.readMap(flags.Arg(1), *strict)
+	if err != nil {
+		return err
+	}
+	d := aliens.DiffMaps(before, after)
+	if err = write(d, c.stdout); err != nil {
+		return err
+	}
+	if !d.Same() {
+		return errDifferent
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dhubler/aliens"
+)
+
+var fmtCommand = &command{
+	name:    "fmt",
+	args:    "[city-map-file ...]",
+	summary: "Rewrite city map files in canonical form",
+	run:     format,
+}
+
+func format(c *cli, flags *flag.FlagSet, args []string) error {
+	strict := flags.Bool("strict", false, "Use a more strict parse that does not back link any cities in opposite directions")
+	omitImplied := flags.Bool("omitImplied", false, "Leave out roads back in the opposite direction that are implied when parse is not strict")
+	check := flags.Bool("check", false, "Only list city map files that are not in canonical form and fail if there are any")
+	if err := c.parse(flags, args, -1); err != nil {
+		return err
+	}
+	if *strict && *omitImplied {
+		fmt.Fprintln(c.stderr, "-omitImplied cannot be used with -strict, roads would be lost")
+		flags.Usage()
+		return errUsage
+	}
+	fnames := flags.Args()
+	if len(fnames) == 0 {
+		fnames = []string{"-"}
+	}
+	write := (*aliens.Map).Write
+	if *omitImplied {
+		write = (*aliens.Map).WriteOmitImplied
+	}
+	invalid, unformatted := 0, 0
+	for _, fname := range fnames {
+		original, formatted, err := formatFile(c, fname, *strict, write)
+		if err != nil {
+			fmt.Fprintf(c.stderr, "%s: %s\n", fname, err)
+			invalid++
+			continue
+		}
+		if *check {
+			if !bytes.Equal(original, formatted) {
+				fmt.Fprintln(c.stdout, fname)
+				unformatted++
+			}
+			continue
+		}
+		if fname == "-" {
+			_, err = c.stdout.Write(formatted)
+		} else if !bytes.Equal(original, formatted) {
+			err = rewriteFile(fname, formatted)
+		}
+		if err != nil {
+			fmt.Fprintf(c.stderr, "%s: %s\n", fname, err)
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d city map(s) could not be formatted", invalid, len(fnames))
+	}
+	if unformatted > 0 {
+		return errDifferent
+	}
+	return nil
+}
+
+// formatFile gives back city map file as it is and in canonical form
+func formatFile(c *cli, fname string, strict bool, write func(*aliens.Map, io.Writer) error) ([]byte, []byte, error) {
+	in, err := c.open(fname)
+	if err != nil {
+		return nil, nil, err
+	}
+	original, err := io.ReadAll(in)
+	in.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := aliens.ParseMap(bytes.NewReader(original), strict)
+	if err != nil {
+		return nil, nil, err
+	}
+	if comments := m.Comments(); len(comments) > 0 {
+		lines := make([]string, len(comments))
+		for i, line := range comments {
+			lines[i] = strconv.Itoa(line)
+		}
+		return nil, nil, fmt.Errorf("comments on line(s) %s would be lost, only comments above the first city are kept", strings.Join(lines, ", "))
+	}
+	var formatted bytes.Buffer
+	if err = write(m, &formatted); err != nil {
+		return nil, nil, err
+	}
+	return original, formatted.Bytes(), nil
+}
+
+// rewriteFile keeping its permissions
+func rewriteFile(fname string, content []byte) error {
+	info, err := os.Stat(fname)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fname, content, info.Mode().Perm())
+}
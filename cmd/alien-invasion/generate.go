@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/dhubler/aliens/mapgen"
+)
+
+var generateCommand = &command{
+	name:    "generate",
+	summary: "Generate a city map in one of several shapes",
+	run:     generate,
+}
+
+func generate(c *cli, flags *flag.FlagSet, args []string) error {
+	topology := flags.String("topology", "grid", "Shape of map: "+strings.Join(mapgen.Topologies, ", "))
+	size := flags.Int("size", 5, "Size of map. Width and height for grid, torus and planar, depth for tree and number of cities for ring")
+	seed := flags.Int64("seed", 0, "Random seed for topologies with random layout")
+	outputFile := flags.String("outputFile", "", "Optional city map output file")
+	if err := c.parse(flags, args, 0); err != nil {
+		return err
+	}
+	m, err := mapgen.Generate(*topology, *size, *seed)
+	if err != nil {
+		return err
+	}
+	return c.writeFile(*outputFile, m.Write)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/dhubler/aliens"
+)
+
+// historyFlags are for reports on what happened during an invasion
+type historyFlags struct {
+	itineraries       *string
+	itinerariesFormat *string
+	cityHistory       *string
+	cityHistoryFormat *string
+}
+
+func addHistoryFlags(flags *flag.FlagSet) *historyFlags {
+	return &historyFlags{
+		itineraries:       flags.String("itineraries", "", "Optional file to write every city each alien went to"),
+		itinerariesFormat: flags.String("itinerariesFormat", "csv", "Format of itineraries file, csv or json"),
+		cityHistory:       flags.String("cityHistory", "", "Optional file to write aliens that came to each city and when it was destroyed and cut off"),
+		cityHistoryFormat: flags.String("cityHistoryFormat", "csv", "Format of city history file, csv or json"),
+	}
+}
+
+// track sets options to keep what is needed for history reports
+func (f *historyFlags) track(options *aliens.Options) error {
+	if *f.itinerariesFormat != "csv" && *f.itinerariesFormat != "json" {
+		return fmt.Errorf("unrecognized itineraries format '%s'", *f.itinerariesFormat)
+	}
+	if *f.cityHistoryFormat != "csv" && *f.cityHistoryFormat != "json" {
+		return fmt.Errorf("unrecognized city history format '%s'", *f.cityHistoryFormat)
+	}
+	options.TrackItineraries = *f.itineraries != ""
+	options.TrackCityHistory = *f.cityHistory != ""
+	return nil
+}
+
+// writeHistory writes history reports asked for
+func (c *cli) writeHistory(f *historyFlags, result *aliens.Result) error {
+	if *f.itineraries != "" {
+		if result.Itineraries == nil {
+			return fmt.Errorf("itineraries were not tracked")
+		}
+		err := c.writeFile(*f.itineraries, func(w io.Writer) error {
+			if *f.itinerariesFormat == "json" {
+				return result.Itineraries.WriteJSON(w)
+			}
+			return result.Itineraries.WriteCSV(w)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if *f.cityHistory != "" {
+		if result.CityHistories == nil {
+			return fmt.Errorf("city history was not tracked")
+		}
+		err := c.writeFile(*f.cityHistory, func(w io.Writer) error {
+			if *f.cityHistoryFormat == "json" {
+				return result.CityHistories.WriteJSON(w)
+			}
+			return result.CityHistories.WriteCSV(w)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
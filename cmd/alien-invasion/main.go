@@ -1,60 +1,242 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"io"
 	"os"
-	"time"
+	"strings"
 
 	"github.com/dhubler/aliens"
 )
 
-var numAliens = flag.Int("numAliens", 10, "Number of aliens invading")
-var numRounds = flag.Int("numRounds", 10000, "Limit the number of rounds the aliens perform before giving up")
-var silent = flag.Bool("silent", false, "Supress log output but still output city report and fallen cities")
-var seed = flag.Int64("seed", 0, "Optional random seed to control pseudo random results.  Default of zero for random each time")
-var strict = flag.Bool("strict", false, "Use a more strict parse that does not back link any cities in opposite directions")
-var outputFile = flag.String("outputFile", "", "Optional remaining cities output file")
+// exit codes
+const (
+	exitOK     = 0
+	exitError  = 1 // could not complete command
+	exitUsage  = 2 // bad command line
+	exitDiffer = 3 // command completed but found differences
+)
+
+type command struct {
+	name    string
+	args    string // positional arguments in usage
+	summary string
+	run     func(c *cli, flags *flag.FlagSet, args []string) error
+}
+
+var commands = []*command{
+	runCommand,
+	validateCommand,
+	generateCommand,
+	analyzeCommand,
+	replayCommand,
+	viewCommand,
+	stepCommand,
+	resumeCommand,
+	batchCommand,
+	convertCommand,
+	fmtCommand,
+	mergeCommand,
+	diffCommand,
+	serveCommand,
+}
+
+// errUsage is returned by commands when command line is invalid. Details are
+// already written to stderr.
+var errUsage = errors.New("usage error")
+
+// cli is the environment commands run in so they can be run in process
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
 
 func main() {
-	var err error
-	cl := flag.CommandLine
-	cl.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage of %s: < city-map-file > report\n", os.Args[0])
-		flag.PrintDefaults()
+	c := &cli{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+	os.Exit(c.main(os.Args[1:]))
+}
+
+// main runs the command given on command line and returns exit code
+func (c *cli) main(args []string) int {
+	cmd := runCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if args[0] == "help" {
+			if len(args) > 1 && findCommand(args[1]) != nil {
+				// same as <command> -h
+				args = []string{args[1], "-h"}
+			} else {
+				c.usage()
+				return exitOK
+			}
+		}
+		cmd = findCommand(args[0])
+		if cmd == nil {
+			fmt.Fprintf(c.stderr, "unrecognized command '%s'\n\n", args[0])
+			c.usage()
+			return exitUsage
+		}
+		args = args[1:]
+	}
+	// with no command, run is assumed. this keeps original usage of
+	//   alien-invasion < city-map-file
+	err := cmd.run(c, c.flagSet(cmd), args)
+	if err == flag.ErrHelp {
+		return exitOK
+	}
+	if err == errUsage {
+		return exitUsage
 	}
-	flag.Parse()
-	if *silent {
-		log.SetOutput(ioutil.Discard)
+	if err == errDifferent {
+		return exitDiffer
 	}
-	options := aliens.Options{
-		NumberAliens:   *numAliens,
-		InvasionRounds: *numRounds,
-		CityMapInput:   os.Stdin,
+	if err != nil {
+		fmt.Fprintf(c.stderr, "error running %s. %s\n", cmd.name, err.Error())
+		return exitError
 	}
-	if *seed == 0 {
-		options.Seed = time.Now().UnixNano()
+	return exitOK
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
 	}
-	if *outputFile != "" {
-		out, err := os.Create(*outputFile)
-		abortOnErr(err)
-		defer func() {
-			abortOnErr(out.Close())
-		}()
-		options.RemaingCitiesOutput = out
-	} else {
-		options.RemaingCitiesOutput = os.Stdout
+	return nil
+}
+
+func (c *cli) usage() {
+	fmt.Fprintf(c.stderr, "Usage: alien-invasion <command> [options] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(c.stderr, "  %-9s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintf(c.stderr, "\nWhen no command is given, run is assumed. City map files that are not given\n")
+	fmt.Fprintf(c.stderr, "are read from stdin. Use 'alien-invasion <command> -h' for command options.\n")
+}
 
-	err = aliens.Invade(options)
-	abortOnErr(err)
+// flagSet has consistent usage and error handling for every command
+func (c *cli) flagSet(cmd *command) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	flags.Usage = func() {
+		usage := strings.TrimSpace(fmt.Sprintf("alien-invasion %s [options] %s", cmd.name, cmd.args))
+		fmt.Fprintf(c.stderr, "Usage: %s\n\n%s\n\nOptions:\n", usage, cmd.summary)
+		flags.PrintDefaults()
+	}
+	return flags
 }
 
-func abortOnErr(err error) {
+// parse command line for a command that takes up to maxArgs positional
+// arguments or any number of arguments if maxArgs is negative
+func (c *cli) parse(flags *flag.FlagSet, args []string, maxArgs int) error {
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return errUsage
+	}
+	if maxArgs >= 0 && flags.NArg() > maxArgs {
+		fmt.Fprintf(c.stderr, "too many arguments %s\n", strings.Join(flags.Args(), " "))
+		flags.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// open input file or stdin if file is not given or is "-". Close is harmless
+// to call on stdin.
+func (c *cli) open(fname string) (io.ReadCloser, error) {
+	if fname == "" || fname == "-" {
+		return io.NopCloser(c.stdin), nil
+	}
+	return os.Open(fname)
+}
+
+// readMap from file following any include lines or from stdin if file is
+// not given or is "-". Maps read from stdin cannot include other maps.
+func (c *cli) readMap(fname string, strict bool) (*aliens.Map, error) {
+	if fname != "" && fname != "-" {
+		return aliens.ParseMapFile(fname, strict)
+	}
+	m, err := aliens.ParseMap(c.stdin, strict)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Includes()) > 0 {
+		return nil, fmt.Errorf("include %s needs map to be read from a file, not stdin", m.Includes()[0])
+	}
+	return m, nil
+}
+
+// readMapText of map file as it is, unless it includes other maps. Those are
+// merged into one map with every road written out, and a strict directive if
+// any map was strict, so map text is all that is needed to run invasion again.
+// Invalid map files are reported with file and line like validate does.
+func (c *cli) readMapText(fname string, strict bool) ([]byte, error) {
+	in, err := c.open(fname)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error runing invasion. %s\n", err.Error())
-		os.Exit(1)
+		return nil, err
+	}
+	text, err := io.ReadAll(in)
+	in.Close()
+	if err != nil {
+		return nil, err
+	}
+	m, err := aliens.ParseMap(bytes.NewReader(text), strict)
+	if err == nil && len(m.Includes()) == 0 {
+		return text, nil
+	}
+	if fname == "" || fname == "-" {
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("include %s needs map to be read from a file, not stdin", m.Includes()[0])
+	}
+	// invalid maps are read again from file so errors say which line is wrong
+	if m, err = aliens.ParseMapFile(fname, strict); err != nil {
+		return nil, err
+	}
+	var merged bytes.Buffer
+	if err = m.Write(&merged); err != nil {
+		return nil, err
+	}
+	return merged.Bytes(), nil
+}
+
+// create output file or stdout if file is not given or is "-"
+func (c *cli) create(fname string) (io.WriteCloser, error) {
+	if fname == "" || fname == "-" {
+		return nopWriteCloser{c.stdout}, nil
+	}
+	return os.Create(fname)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// writeFile creates output file, writes to it with given function and closes
+// file making sure errors on close are not lost
+func (c *cli) writeFile(fname string, write func(w io.Writer) error) error {
+	out, err := c.create(fname)
+	if err != nil {
+		return err
+	}
+	if err = write(out); err != nil {
+		out.Close()
+		return err
 	}
+	return out.Close()
 }
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dhubler/aliens"
+	"github.com/stretchr/testify/assert"
+)
+
+var updateFlag = flag.Bool("update", false, "update expected golden file(s)")
+
+// runCli runs command in process and returns everything it wrote along with
+// exit code in a form suitable for golden files
+func runCli(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	c := &cli{
+		stdin:  strings.NewReader(stdin),
+		stdout: &stdout,
+		stderr: &stderr,
+	}
+	code := c.main(args)
+	return fmt.Sprintf("exit %d\n--- stdout\n%s--- stderr\n%s", code, stdout.String(), stderr.String())
+}
+
+func TestCli(t *testing.T) {
+	smallMap, err := ioutil.ReadFile("../../testdata/small-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+	}{
+		{
+			name: "run",
+			args: []string{"run", "-silent", "-seed", "10", "-numRounds", "10", "../../testdata/small-map.txt"},
+		},
+		{
+			name:  "run-stdin",
+			stdin: string(smallMap),
+			args:  []string{"-silent", "-seed", "10", "-numRounds", "10"},
+		},
+		{
+			name: "run-seed",
+			args: []string{"run", "-silent", "-seed", "20", "-numRounds", "10", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "run-until-over",
+			args: []string{"run", "-silent", "-seed", "20", "-numRounds", "-1", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "run-round-maps",
+			args: []string{"run", "-silent", "-seed", "4", "-numRounds", "10", "-roundMaps", "-", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "run-itineraries",
+			args: []string{"run", "-silent", "-seed", "20", "-numRounds", "3", "-itineraries", "-", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "run-city-history",
+			args: []string{"run", "-silent", "-seed", "20", "-numRounds", "3", "-cityHistory", "-", "-cityHistoryFormat", "json", "../../testdata/small-map.txt"},
+		},
+		{
+			name:  "step",
+			stdin: "\n\nr\nc Boston\ng 2\nc Columbus\nc Paris\nx\ne\n\n",
+			args:  []string{"step", "-seed", "20", "-numAliens", "4", "-numRounds", "3", "../../testdata/small-map.txt"},
+		},
+		{
+			// invasion is only run as far as it is stepped through
+			name:  "step-until-over",
+			stdin: "r\nr\nq\n",
+			args:  []string{"step", "-seed", "20", "-numAliens", "2", "-numRounds", "-1", "-maxRounds", "-1", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "step-no-map",
+			args: []string{"step"},
+		},
+		{
+			name: "run-strict",
+			args: []string{"run", "-silent", "-seed", "10", "-strict", "-numAliens", "2", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "run-scenario",
+			args: []string{"run", "-silent", "-scenario", "../../testdata/small-scenario.yaml"},
+		},
+		{
+			name: "run-scenario-override",
+			args: []string{"run", "-silent", "-scenario", "../../testdata/small-scenario.yaml", "-seed", "3", "-numAliens", "3", "../../testdata/circular-map.txt"},
+		},
+		{
+			name: "run-bad-log-level",
+			args: []string{"run", "-logLevel", "loud", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "run-bad-map",
+			args: []string{"run", "-silent", "../../testdata/bad-map.txt"},
+		},
+		{
+			name: "validate",
+			args: []string{"validate", "../../testdata/small-map.txt", "../../testdata/bad-map.txt"},
+		},
+		{
+			name: "generate",
+			args: []string{"generate", "-topology", "ring", "-size", "3"},
+		},
+		{
+			name: "analyze",
+			args: []string{"analyze", "-strict", "../../testdata/circular-map.txt"},
+		},
+		{
+			name: "analyze-json",
+			args: []string{"analyze", "-format", "json", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "batch",
+			args: []string{"batch", "-runs", "3", "-seed", "1", "-numRounds", "10", "../../testdata/medium-map.txt"},
+		},
+		{
+			name: "batch-parallel",
+			args: []string{"batch", "-runs", "12", "-parallel", "4", "-seed", "1", "-numRounds", "10", "../../testdata/medium-map.txt"},
+		},
+		{
+			name: "convert",
+			args: []string{"convert", "../../testdata/small-map.txt"},
+		},
+		{
+			name:  "convert-skipped",
+			stdin: "from,to,direction\nBoston,Albany,west\nBoston,Paris,\nBoston,Rome,sideways\nBoston,Heaven,up\n",
+			args:  []string{"convert", "-from", "csv", "-to", "text"},
+		},
+		{
+			name:  "convert-strict",
+			stdin: "from,to,direction\nA,B,east\nB,C,east\n",
+			args:  []string{"convert", "-strict", "-from", "csv", "-to", "text"},
+		},
+		{
+			name:  "fmt",
+			stdin: "Boston west=Albany\nBangor south=Boston\nBoston north=Bangor\nBoston west=Albany\n",
+			args:  []string{"fmt"},
+		},
+		{
+			name:  "fmt-quoted",
+			stdin: "  \"New York\"\tnorth=\"New Haven\"   west=Albany\nS\u00e3o  east=\"S\\u00e3o Paulo\"\n",
+			args:  []string{"fmt"},
+		},
+		{
+			// comments below the header cannot be kept so map is left alone
+			name:  "fmt-comments",
+			stdin: "# cities\nA north=B # trailing\n# middle\nB\n",
+			args:  []string{"fmt"},
+		},
+		{
+			name:  "fmt-omit-implied",
+			stdin: "Boston west=Albany\nBangor south=Boston\nBoston north=Bangor\n",
+			args:  []string{"fmt", "-omitImplied"},
+		},
+		{
+			name:  "fmt-check",
+			stdin: "Boston west=Albany\n",
+			args:  []string{"fmt", "-check"},
+		},
+		{
+			name: "merge",
+			args: []string{"merge", "../../testdata/regions/maine.txt", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "merge-strict",
+			args: []string{"merge", "../../testdata/regions/maine.txt", "../../testdata/regions/one-way.txt"},
+		},
+		{
+			name: "merge-conflict",
+			args: []string{"merge", "../../testdata/regions/new-england.txt", "../../testdata/regions/conflict.txt"},
+		},
+		{
+			name: "merge-no-files",
+			args: []string{"merge"},
+		},
+		{
+			name: "run-include",
+			args: []string{"run", "-silent", "-seed", "10", "-numRounds", "10", "../../testdata/regions/new-england.txt"},
+		},
+		{
+			name:  "run-include-stdin",
+			stdin: "include maine.txt\nBoston north=Bangor\n",
+			args:  []string{"run", "-silent"},
+		},
+		{
+			name:  "diff",
+			stdin: "Boston north=Bangor west=Worcester\nNewYork west=Columbus\nSalem\n",
+			args:  []string{"diff", "../../testdata/small-map.txt", "-"},
+		},
+		{
+			name:  "diff-json",
+			stdin: "Boston north=Bangor west=Worcester\nNewYork west=Columbus\nSalem\n",
+			args:  []string{"diff", "-format", "json", "../../testdata/small-map.txt", "-"},
+		},
+		{
+			name:  "diff-same",
+			stdin: string(smallMap),
+			args:  []string{"diff", "-", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "diff-bad-map",
+			args: []string{"diff", "../../testdata/small-map.txt", "../../testdata/bad-map.txt"},
+		},
+		{
+			name: "diff-one-file",
+			args: []string{"diff", "../../testdata/small-map.txt"},
+		},
+		{
+			name: "help",
+			args: []string{"help"},
+		},
+		{
+			name: "bad-command",
+			args: []string{"bogus"},
+		},
+		{
+			name: "bad-flag",
+			args: []string{"run", "-bogus"},
+		},
+		{
+			name: "too-many-args",
+			args: []string{"analyze", "a.txt", "b.txt"},
+		},
+	}
+	for _, test := range tests {
+		actual := runCli(t, test.stdin, test.args...)
+		aliens.Golden(t, *updateFlag, "testdata/"+test.name+".golden", strings.NewReader(actual))
+	}
+}
+
+func TestCliFmt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alien-invasion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	formatted := filepath.Join(dir, "formatted.txt")
+	assert.NoError(t, ioutil.WriteFile(formatted, []byte("Albany east=Boston\nBoston west=Albany\n"), 0644))
+	unformatted := filepath.Join(dir, "unformatted.txt")
+	assert.NoError(t, ioutil.WriteFile(unformatted, []byte("Boston west=Albany\n"), 0644))
+
+	actual := runCli(t, "", "fmt", "-check", formatted, unformatted)
+	assert.Equal(t, "exit 3\n--- stdout\n"+unformatted+"\n--- stderr\n", actual)
+
+	actual = runCli(t, "", "fmt", formatted, unformatted)
+	assert.Equal(t, "exit 0\n--- stdout\n--- stderr\n", actual)
+	content, err := ioutil.ReadFile(unformatted)
+	assert.NoError(t, err)
+	assert.Equal(t, "Albany east=Boston\nBoston west=Albany\n", string(content))
+	assert.Equal(t, "exit 0\n--- stdout\n--- stderr\n", runCli(t, "", "fmt", "-check", formatted, unformatted))
+}
+
+func TestCliRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alien-invasion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	recording := filepath.Join(dir, "invasion.json")
+	// recording of map that includes other maps has all of map
+	for _, mapFile := range []string{"../../testdata/small-map.txt", "../../testdata/regions/new-england.txt"} {
+		original := runCli(t, "", "run", "-silent", "-record", recording, mapFile)
+		replayed := runCli(t, "", "replay", "-silent", recording)
+		assert.Equal(t, original, replayed)
+	}
+}
+
+// pauseWriter cancels invasion after first city falls
+type pauseWriter struct {
+	bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *pauseWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return w.Buffer.Write(p)
+}
+
+func TestCliResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alien-invasion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	mapFile := "../../testdata/medium-map.txt"
+	original := runCli(t, "", "run", "-silent", "-seed", "1", "-numAliens", "20", "-numRounds", "10", mapFile)
+
+	// pause same invasion after first city falls
+	f, err := os.Open(mapFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	paused := &pauseWriter{cancel: cancel}
+	invasion, err := aliens.NewInvasion(aliens.Options{
+		Seed:               1,
+		NumberAliens:       20,
+		InvasionRounds:     10,
+		CityMapInput:       f,
+		FallenCitiesOutput: paused,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = invasion.Run(ctx); err == nil {
+		t.Fatal("expected invasion to be interrupted")
+	}
+	snapshot := filepath.Join(dir, "snapshot.json")
+	sf, err := os.Create(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := invasion.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, state.Write(sf))
+	assert.NoError(t, sf.Close())
+
+	resumed := runCli(t, "", "resume", "-silent", snapshot)
+	assert.Equal(t, original, strings.Replace(resumed, "--- stdout\n", "--- stdout\n"+paused.String(), 1))
+}
+
+func TestCliRoundMapsDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alien-invasion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rounds := filepath.Join(dir, "rounds") + "/"
+	runCli(t, "", "run", "-silent", "-seed", "1", "-numAliens", "4", "-numRounds", "10", "-roundMapsEvery", "4", "-roundMaps", rounds, "../../testdata/medium-map.txt")
+	files, err := filepath.Glob(rounds + "*")
+	assert.NoError(t, err)
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	assert.Equal(t, []string{"round-00000.txt", "round-00004.txt", "round-00008.txt", "round-00010.txt"}, files)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dhubler/aliens"
+)
+
+var mergeCommand = &command{
+	name:    "merge",
+	args:    "city-map-file ...",
+	summary: "Combine city map files into one map",
+	run:     merge,
+}
+
+func merge(c *cli, flags *flag.FlagSet, args []string) error {
+	strict := flags.Bool("strict", false, "Use a more strict parse that does not back link any cities in opposite directions")
+	outputFile := flags.String("outputFile", "", "Optional merged city map output file")
+	if err := c.parse(flags, args, -1); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(c.stderr, "at least one city map file is required")
+		flags.Usage()
+		return errUsage
+	}
+	m, err := aliens.MergeMapFiles(flags.Args(), *strict)
+	var conflicts aliens.MapConflicts
+	if errors.As(err, &conflicts) {
+		for _, conflict := range conflicts {
+			fmt.Fprintln(c.stderr, conflict)
+		}
+		return fmt.Errorf("%d conflicting road(s), maps were not merged", len(conflicts))
+	}
+	if err != nil {
+		return err
+	}
+	return c.writeFile(*outputFile, func(out io.Writer) error {
+		if _, err := fmt.Fprintf(out, "# merged from %s\n", strings.Join(flags.Args(), " ")); err != nil {
+			return err
+		}
+		return m.Write(out)
+	})
+}
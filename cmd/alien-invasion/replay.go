@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"github.com/dhubler/aliens"
+)
+
+var replayCommand = &command{
+	name:    "replay",
+	args:    "[recording-file]",
+	summary: "Replay an invasion recorded by run -record",
+	run:     replay,
+}
+
+func replay(c *cli, flags *flag.FlagSet, args []string) error {
+	logging := addLogFlags(flags)
+	history := addHistoryFlags(flags)
+	outputFile := flags.String("outputFile", "", "Optional remaining cities output file")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	logger, err := c.logger(logging)
+	if err != nil {
+		return err
+	}
+	in, err := c.open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	rec, err := aliens.ReadRecording(in)
+	in.Close()
+	if err != nil {
+		return err
+	}
+	options := rec.Options()
+	if err = history.track(&options); err != nil {
+		return err
+	}
+	var result *aliens.Result
+	err = c.writeFile(*outputFile, func(out io.Writer) error {
+		options.RemaingCitiesOutput = out
+		options.FallenCitiesOutput = c.stdout
+		options.Logger = logger
+		var err error
+		result, err = aliens.Invade(options)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	c.summary(result)
+	return c.writeHistory(history, result)
+}
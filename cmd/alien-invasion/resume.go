@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"github.com/dhubler/aliens"
+)
+
+var resumeCommand = &command{
+	name:    "resume",
+	args:    "[snapshot-file]",
+	summary: "Resume an invasion saved by run -snapshot",
+	run:     resume,
+}
+
+func resume(c *cli, flags *flag.FlagSet, args []string) error {
+	logging := addLogFlags(flags)
+	history := addHistoryFlags(flags)
+	roundMapping := addRoundMapFlags(flags)
+	outputFile := flags.String("outputFile", "", "Optional remaining cities output file")
+	timeBudget := flags.Duration("timeBudget", 0, "Optional wall clock time limit on rest of invasion like 30s or 5m. Default of zero for no limit")
+	snapshotFile := flags.String("snapshot", "", "Optional file to save invasion to when it is interrupted again")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	logger, err := c.logger(logging)
+	if err != nil {
+		return err
+	}
+	in, err := c.open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	snapshot, err := aliens.ReadSnapshot(in)
+	in.Close()
+	if err != nil {
+		return err
+	}
+	options := aliens.Options{
+		FallenCitiesOutput: c.stdout,
+		Logger:             logger,
+		TimeBudget:         *timeBudget,
+	}
+	// itineraries are only there if original invasion tracked them
+	if err = history.track(&options); err != nil {
+		return err
+	}
+	closeRoundMaps, err := c.roundMaps(roundMapping, &options)
+	if err != nil {
+		return err
+	}
+	var result *aliens.Result
+	err = c.writeFile(*outputFile, func(out io.Writer) error {
+		options.RemaingCitiesOutput = out
+		invasion, err := aliens.ResumeInvasion(snapshot, options)
+		if err != nil {
+			return err
+		}
+		result, err = c.invade(invasion, *snapshotFile)
+		return err
+	})
+	if closeErr := closeRoundMaps(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	c.summary(result)
+	return c.writeHistory(history, result)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dhubler/aliens"
+)
+
+// roundMapFlags are common to commands that can write map of every round
+type roundMapFlags struct {
+	path   *string
+	every  *int
+	format *string
+}
+
+func addRoundMapFlags(flags *flag.FlagSet) *roundMapFlags {
+	return &roundMapFlags{
+		path:   flags.String("roundMaps", "", "Optional file to write map and aliens of each round to, one round after another. Directory or path ending in / to write a file for each round instead"),
+		every:  flags.Int("roundMapsEvery", 1, "Write map every so many rounds, map is always written after landing and at end of invasion"),
+		format: flags.String("roundMapsFormat", "text", "Format of round maps, text or json"),
+	}
+}
+
+// roundMaps sets up options to write round maps, call close when invasion is
+// over
+func (c *cli) roundMaps(f *roundMapFlags, options *aliens.Options) (close func() error, err error) {
+	options.RoundMapsEvery = *f.every
+	path := *f.path
+	if path == "" {
+		return func() error { return nil }, nil
+	}
+	write, ext := (*aliens.RoundMap).Write, "txt"
+	switch *f.format {
+	case "text":
+	case "json":
+		write, ext = (*aliens.RoundMap).WriteJSON, "json"
+	default:
+		return nil, fmt.Errorf("unrecognized round maps format '%s'", *f.format)
+	}
+	if info, err := os.Stat(path); strings.HasSuffix(path, "/") || (err == nil && info.IsDir()) {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+		options.RoundMaps = func(m *aliens.RoundMap) error {
+			fname := filepath.Join(path, fmt.Sprintf("round-%05d.%s", m.Round, ext))
+			return c.writeFile(fname, func(w io.Writer) error {
+				return write(m, w)
+			})
+		}
+		return func() error { return nil }, nil
+	}
+	out, err := c.create(path)
+	if err != nil {
+		return nil, err
+	}
+	options.RoundMaps = func(m *aliens.RoundMap) error {
+		return write(m, out)
+	}
+	return out.Close, nil
+}
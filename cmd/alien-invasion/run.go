@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/dhubler/aliens"
+)
+
+var runCommand = &command{
+	name:    "run",
+	args:    "[city-map-file]",
+	summary: "Run an invasion and report remaining cities",
+	run:     run,
+}
+
+func run(c *cli, flags *flag.FlagSet, args []string) error {
+	numAliens := flags.Int("numAliens", aliens.DefaultNumberAliens, "Number of aliens invading")
+	strategy := flags.String("strategy", aliens.StrategyRandom, "How aliens pick which road to take: "+strings.Join(aliens.Strategies, ", "))
+	numRounds := flags.Int("numRounds", aliens.DefaultInvasionRounds, "Limit the number of rounds the aliens perform before giving up or -1 to go until no more cities can be destroyed")
+	maxRounds := flags.Int("maxRounds", 0, "Most rounds any invasion can run or -1 for no limit. Default of zero for 10000")
+	logging := addLogFlags(flags)
+	history := addHistoryFlags(flags)
+	roundMapping := addRoundMapFlags(flags)
+	seed := flags.Int64("seed", 0, "Optional random seed to control pseudo random results.  Default of zero for random each time")
+	strict := flags.Bool("strict", false, "Use a more strict parse that does not back link any cities in opposite directions")
+	outputFile := flags.String("outputFile", "", "Optional remaining cities output file")
+	reportFile := flags.String("reportFile", "", "Optional report file on how remaining cities were cut off from each other")
+	reportFormat := flags.String("reportFormat", "text", "Format of report file, text or json")
+	recordFile := flags.String("record", "", "Optional file to record invasion so it can be replayed")
+	scenarioFile := flags.String("scenario", "", "Optional scenario file with map, aliens, rules and output files. Flags override values in scenario file")
+	timeBudget := flags.Duration("timeBudget", 0, "Optional wall clock time limit on invasion like 30s or 5m. Default of zero for no limit")
+	snapshotFile := flags.String("snapshot", "", "Optional file to save invasion to when it is interrupted so it can be picked up again with resume")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	mapFile := flags.Arg(0)
+	var alienNames []string
+	if *scenarioFile != "" {
+		s, err := aliens.LoadScenario(*scenarioFile)
+		if err != nil {
+			return err
+		}
+		// only values not given on command line are taken from scenario
+		given := make(map[string]bool)
+		flags.Visit(func(f *flag.Flag) {
+			given[f.Name] = true
+		})
+		if mapFile == "" {
+			mapFile = s.Map
+		}
+		if !given["numAliens"] {
+			if s.Aliens.Count != 0 {
+				*numAliens = s.Aliens.Count
+			}
+			alienNames = s.Aliens.Names
+		}
+		if !given["strategy"] && s.Aliens.Strategy != "" {
+			*strategy = s.Aliens.Strategy
+		}
+		if !given["numRounds"] && s.Rules.Rounds != 0 {
+			*numRounds = s.Rules.Rounds
+		}
+		if !given["maxRounds"] && s.Rules.MaxRounds != 0 {
+			*maxRounds = s.Rules.MaxRounds
+		}
+		if !given["seed"] {
+			*seed = s.Seed
+		}
+		if !given["strict"] {
+			*strict = s.Rules.Strict
+		}
+		if !given["outputFile"] {
+			*outputFile = s.Output.Remaining
+		}
+		if !given["reportFile"] {
+			*reportFile = s.Output.Report
+		}
+		if !given["reportFormat"] && s.Output.ReportFormat != "" {
+			*reportFormat = s.Output.ReportFormat
+		}
+		if !given["record"] {
+			*recordFile = s.Output.Record
+		}
+	}
+	if *reportFormat != "text" && *reportFormat != "json" {
+		return fmt.Errorf("unrecognized report format '%s'", *reportFormat)
+	}
+	logger, err := c.logger(logging)
+	if err != nil {
+		return err
+	}
+
+	cityMap, err := c.readMapText(mapFile, *strict)
+	if err != nil {
+		return err
+	}
+	options := aliens.Options{
+		NumberAliens:   *numAliens,
+		AlienNames:     alienNames,
+		Strategy:       *strategy,
+		InvasionRounds: *numRounds,
+		MaxRounds:      *maxRounds,
+		CityMapInput:   bytes.NewReader(cityMap),
+		StrictMapParse: *strict,
+		Seed:           *seed,
+		TimeBudget:     *timeBudget,
+
+		FallenCitiesOutput: c.stdout,
+		Logger:             logger,
+	}
+	if options.Seed == 0 {
+		options.Seed = time.Now().UnixNano()
+	}
+	if err = history.track(&options); err != nil {
+		return err
+	}
+	closeRoundMaps, err := c.roundMaps(roundMapping, &options)
+	if err != nil {
+		return err
+	}
+	var result *aliens.Result
+	err = c.writeFile(*outputFile, func(out io.Writer) error {
+		options.RemaingCitiesOutput = out
+		invasion, err := aliens.NewInvasion(options)
+		if err != nil {
+			return err
+		}
+		result, err = c.invade(invasion, *snapshotFile)
+		return err
+	})
+	if closeErr := closeRoundMaps(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	c.summary(result)
+	if err = c.writeHistory(history, result); err != nil {
+		return err
+	}
+	if *reportFile != "" {
+		err = c.writeFile(*reportFile, func(out io.Writer) error {
+			if *reportFormat == "json" {
+				return result.Fragmentation.WriteJSON(out)
+			}
+			return result.Fragmentation.Write(out)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if *recordFile != "" {
+		return c.writeFile(*recordFile, aliens.NewRecording(options, string(cityMap)).Write)
+	}
+	return nil
+}
+
+// invade runs invasion until it is over. Interrupt from keyboard or running
+// out of time stops invasion but still reports how far it got and saves
+// invasion to snapshot file if one is given.
+func (c *cli) invade(invasion *aliens.Invasion, snapshotFile string) (*aliens.Result, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := invasion.Run(ctx)
+	if err != nil && result != nil {
+		c.summary(result)
+		if snapshotFile != "" {
+			snapshot, err := invasion.Snapshot()
+			if err != nil {
+				return nil, err
+			}
+			if err = c.writeFile(snapshotFile, snapshot.Write); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, err
+}
+
+// summary is always written to stderr, even when silent, so any invasion can
+// be reproduced from its seed
+func (c *cli) summary(result *aliens.Result) {
+	fmt.Fprintf(c.stderr, "seed %d, %d round(s), %d cities left, %d alien(s) left, %d alien(s) trapped, ended %s\n",
+		result.Seed, result.Rounds, len(result.Remaining), result.AliensLeft, result.AliensTrapped, result.Ended)
+}
+
+// logFlags are common to commands that run invasions
+type logFlags struct {
+	silent *bool
+	level  *string
+}
+
+func addLogFlags(flags *flag.FlagSet) *logFlags {
+	return &logFlags{
+		silent: flags.Bool("silent", false, "Supress log output but still output city report and fallen cities"),
+		level:  flags.String("logLevel", "debug", "Least important log output to show: debug, info, warn or error"),
+	}
+}
+
+// logger writes invasion log output to stderr
+func (c *cli) logger(f *logFlags) (*slog.Logger, error) {
+	if *f.silent {
+		return nil, nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*f.level)); err != nil {
+		return nil, fmt.Errorf("unrecognized log level '%s'", *f.level)
+	}
+	return slog.New(slog.NewTextHandler(c.stderr, &slog.HandlerOptions{Level: level})), nil
+}
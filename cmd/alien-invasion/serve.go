@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhubler/aliens"
+)
+
+var serveCommand = &command{
+	name:    "serve",
+	summary: "Run invasions for clients of an HTTP JSON API",
+	run:     serve,
+}
+
+// states of a run
+const (
+	runQueued      = "queued"
+	runRunning     = "running"
+	runDone        = "done"
+	runFailed      = "failed"
+	runInterrupted = "interrupted"
+)
+
+// largest request body accepted, mostly the city map
+const maxRequestSize = 10 << 20
+
+// most aliens a client can ask for, every alien is set up before invasion
+// starts
+const maxAliens = 1000000
+
+// most rounds a client can ask for when server has no time budget so every
+// invasion frees its worker eventually
+const maxRoundsNoBudget = aliens.DefaultMaxRounds
+
+func serve(c *cli, flags *flag.FlagSet, args []string) error {
+	addr := flags.String("addr", "localhost:8080", "Address to listen on")
+	workers := flags.Int("workers", runtime.NumCPU(), "Number of invasions to run at the same time")
+	queue := flags.Int("queue", 100, "Number of invasions that can wait for a worker, more are turned away until there is room")
+	keep := flags.Int("keep", 1000, "Number of finished invasions to keep results of, oldest are forgotten first")
+	timeBudget := flags.Duration("timeBudget", time.Minute, "Wall clock time limit on each invasion or zero for no limit")
+	logging := addLogFlags(flags)
+	if err := c.parse(flags, args, 0); err != nil {
+		return err
+	}
+	if *workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", *workers)
+	}
+	if *queue < 0 {
+		return fmt.Errorf("queue cannot be negative, got %d", *queue)
+	}
+	if *keep < 1 {
+		return fmt.Errorf("keep must be at least 1, got %d", *keep)
+	}
+	logger, err := c.logger(logging)
+	if err != nil {
+		return err
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s := newServer(ctx, *workers, *queue, *keep, *timeBudget, logger)
+	httpServer := &http.Server{Handler: s}
+	go func() {
+		<-ctx.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+	logger.Info("serving", "addr", listener.Addr().String())
+	if err = httpServer.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	s.wait()
+	return nil
+}
+
+// server queues invasions posted by clients and runs them with a fixed
+// number of workers
+type server struct {
+	log        *slog.Logger
+	timeBudget time.Duration
+	keep       int
+	queue      chan *serverRun
+	workers    sync.WaitGroup
+	done       <-chan struct{} // server is stopping
+
+	mu       sync.Mutex
+	runs     map[string]*serverRun
+	finished []string // ids of finished runs, oldest first
+	nextID   int
+}
+
+// serverRun is an invasion posted by a client. Fields other than seed,
+// invasion and stream are guarded by server mutex.
+type serverRun struct {
+	id        string
+	seed      int64
+	invasion  *aliens.Invasion
+	stream    *stream
+	status    string
+	err       error
+	result    *aliens.Result
+	remaining bytes.Buffer
+	fallen    bytes.Buffer
+}
+
+// newServer starts workers that run invasions until context is done
+func newServer(ctx context.Context, workers, queue, keep int, timeBudget time.Duration, logger *slog.Logger) *server {
+	s := &server{
+		log:        logger,
+		timeBudget: timeBudget,
+		keep:       keep,
+		queue:      make(chan *serverRun, queue),
+		runs:       make(map[string]*serverRun),
+		done:       ctx.Done(),
+	}
+	for i := 0; i < workers; i++ {
+		s.workers.Add(1)
+		go s.work(ctx)
+	}
+	return s
+}
+
+// wait for workers to finish invasions they were running
+func (s *server) wait() {
+	s.workers.Wait()
+}
+
+func (s *server) work(ctx context.Context) {
+	defer s.workers.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case run := <-s.queue:
+			s.invade(ctx, run)
+		}
+	}
+}
+
+func (s *server) invade(ctx context.Context, run *serverRun) {
+	s.mu.Lock()
+	run.status = runRunning
+	s.mu.Unlock()
+	s.log.Debug("run starting", "id", run.id)
+	result, err := s.runInvasion(ctx, run)
+	// clients watching are sent status after stream is closed
+	defer run.stream.close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run.result, run.err = result, err
+	switch {
+	case err == nil:
+		run.status = runDone
+	case result != nil:
+		run.status = runInterrupted
+	default:
+		run.status = runFailed
+	}
+	s.log.Info("run finished", "id", run.id, "status", run.status)
+	s.finished = append(s.finished, run.id)
+	for len(s.finished) > s.keep {
+		delete(s.runs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+// runInvasion turning a panic into an error so one bad invasion cannot take
+// down the server along with every other invasion
+func (s *server) runInvasion(ctx context.Context, run *serverRun) (result *aliens.Result, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.Error("run panicked", "id", run.id, "panic", r, "stack", string(debug.Stack()))
+			result, err = nil, fmt.Errorf("invasion failed. %v", r)
+		}
+	}()
+	return run.invasion.Run(ctx)
+}
+
+// runRequest is what clients post to start an invasion. Fields left out take
+// the same defaults as the run command.
+type runRequest struct {
+	Map        string   `json:"map"`
+	Strict     bool     `json:"strict"`
+	NumAliens  int      `json:"numAliens"`
+	AlienNames []string `json:"alienNames"`
+	NumRounds  int      `json:"numRounds"`
+	MaxRounds  int      `json:"maxRounds"`
+	Seed       int64    `json:"seed"`
+}
+
+// runStatus is what clients get back when they post or poll an invasion
+type runStatus struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Seed   int64  `json:"seed"`
+	Rounds int    `json:"rounds"`
+	Ended  string `json:"ended,omitempty"`
+}
+
+// runStats are the same statistics batch reports on an invasion
+type runStats struct {
+	Seed          int64                 `json:"seed"`
+	Rounds        int                   `json:"rounds"`
+	Ended         string                `json:"ended"`
+	CitiesLeft    int                   `json:"citiesLeft"`
+	CitiesFallen  int                   `json:"citiesFallen"`
+	AliensKilled  int                   `json:"aliensKilled"`
+	AliensTrapped int                   `json:"aliensTrapped"`
+	AliensLeft    int                   `json:"aliensLeft"`
+	Fragmentation *aliens.Fragmentation `json:"fragmentation"`
+}
+
+// ServeHTTP routes
+//   POST /runs                  start an invasion, returns status with run id
+//   GET  /runs/{id}             status of invasion
+//   GET  /runs/{id}/remaining   remaining cities in city map format
+//   GET  /runs/{id}/fallen      fallen cities in fallen city format
+//   GET  /runs/{id}/stats       statistics as JSON
+//   GET  /runs/{id}/events      Server-Sent Events as invasion goes on
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if path[0] != "runs" || len(path) > 3 {
+		http.NotFound(w, r)
+		return
+	}
+	if len(path) == 1 {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		s.post(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	if len(path) == 3 && path[2] != "remaining" && path[2] != "fallen" && path[2] != "stats" && path[2] != "events" {
+		http.NotFound(w, r)
+		return
+	}
+	// run results do not change once run is finished so only status needs
+	// to be read while locked
+	s.mu.Lock()
+	run, exists := s.runs[path[1]]
+	var status *runStatus
+	var result *aliens.Result
+	if exists {
+		status, result = run.statusLocked(), run.result
+	}
+	s.mu.Unlock()
+	if !exists {
+		http.Error(w, fmt.Sprintf("no run '%s'", path[1]), http.StatusNotFound)
+		return
+	}
+	if len(path) == 2 {
+		writeJSON(w, http.StatusOK, status)
+		return
+	}
+	if path[2] == "events" {
+		s.watch(w, r, run)
+		return
+	}
+	if result == nil {
+		// results are partial until run is finished
+		http.Error(w, fmt.Sprintf("run '%s' is %s", status.ID, status.Status), http.StatusConflict)
+		return
+	}
+	switch path[2] {
+	case "remaining":
+		writeText(w, run.remaining.Bytes())
+	case "fallen":
+		writeText(w, run.fallen.Bytes())
+	case "stats":
+		writeJSON(w, http.StatusOK, &runStats{
+			Seed:          result.Seed,
+			Rounds:        result.Rounds,
+			Ended:         result.Ended,
+			CitiesLeft:    len(result.Remaining),
+			CitiesFallen:  len(result.Fallen),
+			AliensKilled:  result.AliensKilled(),
+			AliensTrapped: result.AliensTrapped,
+			AliensLeft:    result.AliensLeft,
+			Fragmentation: result.Fragmentation,
+		})
+	}
+}
+
+// post checks map and options before queuing invasion so clients find out
+// about mistakes right away
+func (s *server) post(w http.ResponseWriter, r *http.Request) {
+	req := runRequest{NumAliens: aliens.DefaultNumberAliens, NumRounds: aliens.DefaultInvasionRounds}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request. %s", err), http.StatusBadRequest)
+		return
+	}
+	m, err := aliens.ParseMap(strings.NewReader(req.Map), req.Strict)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid map. %s", err), http.StatusBadRequest)
+		return
+	}
+	if len(m.Includes()) > 0 {
+		http.Error(w, fmt.Sprintf("invalid map. include %s is not allowed, send whole map", m.Includes()[0]), http.StatusBadRequest)
+		return
+	}
+	if req.NumAliens < 0 || req.NumAliens > maxAliens {
+		http.Error(w, fmt.Sprintf("invalid request. numAliens must be between 0 and %d, got %d", maxAliens, req.NumAliens), http.StatusBadRequest)
+		return
+	}
+	if req.NumRounds == aliens.UntilOver && req.MaxRounds == aliens.NoRoundLimit && s.timeBudget <= 0 {
+		http.Error(w, fmt.Sprintf("invalid request. %s", aliens.ErrNoRoundLimit), http.StatusBadRequest)
+		return
+	}
+	if rounds := mostRounds(req.NumRounds, req.MaxRounds); s.timeBudget <= 0 && (rounds == aliens.NoRoundLimit || rounds > maxRoundsNoBudget) {
+		http.Error(w, fmt.Sprintf("invalid request. server has no time budget, numRounds and maxRounds cannot be more than %d", maxRoundsNoBudget), http.StatusBadRequest)
+		return
+	}
+	if req.Seed == 0 {
+		req.Seed = time.Now().UnixNano()
+	}
+	run := &serverRun{
+		seed:   req.Seed,
+		status: runQueued,
+		stream: newStream(),
+	}
+	// alien names and empty maps are only checked when invasion is set up.
+	// outputs are only read once run is finished
+	run.invasion, err = aliens.NewInvasion(aliens.Options{
+		NumberAliens:        req.NumAliens,
+		AlienNames:          req.AlienNames,
+		InvasionRounds:      req.NumRounds,
+		MaxRounds:           req.MaxRounds,
+		Seed:                req.Seed,
+		CityMap:             m,
+		TimeBudget:          s.timeBudget,
+		RemaingCitiesOutput: &run.remaining,
+		FallenCitiesOutput:  &run.fallen,
+		Events:              run.stream.publish,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request. %s", err), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.nextID++
+	run.id = strconv.Itoa(s.nextID)
+	select {
+	case s.queue <- run:
+	default:
+		s.mu.Unlock()
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "too many invasions waiting, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	s.runs[run.id] = run
+	status := run.statusLocked()
+	s.mu.Unlock()
+	s.log.Debug("run queued", "id", run.id)
+	// written unlocked so a slow client does not hold up everyone else
+	w.Header().Set("Location", "/runs/"+run.id)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// mostRounds an invasion can run given rounds and max rounds asked for or
+// NoRoundLimit when nothing stops it
+func mostRounds(numRounds, maxRounds int) int {
+	if maxRounds == 0 {
+		maxRounds = aliens.DefaultMaxRounds
+	}
+	if numRounds == aliens.UntilOver || (maxRounds != aliens.NoRoundLimit && numRounds > maxRounds) {
+		return maxRounds
+	}
+	return numRounds
+}
+
+// statusLocked must be called with server mutex held
+func (run *serverRun) statusLocked() *runStatus {
+	status := &runStatus{
+		ID:     run.id,
+		Status: run.status,
+		Seed:   run.seed,
+	}
+	if run.err != nil {
+		status.Error = run.err.Error()
+	}
+	if run.result != nil {
+		status.Rounds = run.result.Rounds
+		status.Ended = run.result.Ended
+	}
+	return status
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	// too late to change response on error, client went away most likely
+	enc.Encode(v)
+}
+
+func writeText(w http.ResponseWriter, text []byte) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(text)
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dhubler/aliens"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T, workers, queue int) (*server, *httptest.Server) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newServer(ctx, workers, queue, 10, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ts := httptest.NewServer(s)
+	t.Cleanup(func() {
+		cancel()
+		ts.Close()
+		s.wait()
+	})
+	return s, ts
+}
+
+// request returns status code and body of response
+func request(t *testing.T, method, url, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(content)
+}
+
+func postRun(t *testing.T, url string, req runRequest) (int, *runStatus) {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, resp := request(t, http.MethodPost, url+"/runs", string(body))
+	var status runStatus
+	json.Unmarshal([]byte(resp), &status)
+	return code, &status
+}
+
+func TestServe(t *testing.T) {
+	smallMap, err := ioutil.ReadFile("../../testdata/small-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ts := newTestServer(t, 2, 10)
+	code, status := postRun(t, ts.URL, runRequest{Map: string(smallMap), NumAliens: 10, NumRounds: 3, Seed: 20})
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, "1", status.ID)
+
+	for status.Status == runQueued || status.Status == runRunning {
+		time.Sleep(10 * time.Millisecond)
+		var resp string
+		code, resp = request(t, http.MethodGet, ts.URL+"/runs/1", "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.NoError(t, json.Unmarshal([]byte(resp), status))
+	}
+	assert.Equal(t, runStatus{ID: "1", Status: runDone, Seed: 20, Rounds: 3, Ended: "rounds"}, *status)
+
+	// same as run command
+	expected := runCli(t, "", "run", "-silent", "-seed", "20", "-numRounds", "3", "../../testdata/small-map.txt")
+	_, remaining := request(t, http.MethodGet, ts.URL+"/runs/1/remaining", "")
+	_, fallen := request(t, http.MethodGet, ts.URL+"/runs/1/fallen", "")
+	assert.Contains(t, expected, "--- stdout\n"+fallen+remaining+"--- stderr")
+
+	code, resp := request(t, http.MethodGet, ts.URL+"/runs/1/stats", "")
+	assert.Equal(t, http.StatusOK, code)
+	var stats runStats
+	assert.NoError(t, json.Unmarshal([]byte(resp), &stats))
+	assert.Equal(t, 4, stats.CitiesFallen)
+	assert.Equal(t, 8, stats.AliensKilled)
+	assert.Equal(t, 2, stats.CitiesLeft)
+
+	code, _ = postRun(t, ts.URL, runRequest{Map: "Boston sideways=Albany"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	// clients cannot read files on server
+	code, _ = postRun(t, ts.URL, runRequest{Map: "include /etc/passwd\nBoston"})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = request(t, http.MethodPost, ts.URL+"/runs", `{"mapp": ""}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = postRun(t, ts.URL, runRequest{Map: ""})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = postRun(t, ts.URL, runRequest{Map: "Boston", NumAliens: maxAliens + 1})
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = request(t, http.MethodGet, ts.URL+"/runs/99", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = request(t, http.MethodGet, ts.URL+"/runs/1/nope", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = request(t, http.MethodDelete, ts.URL+"/runs/1", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
+
+func TestServeNoTimeBudget(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newServer(ctx, 1, 10, 10, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	ts := httptest.NewServer(s)
+	defer func() {
+		cancel()
+		ts.Close()
+		s.wait()
+	}()
+	tests := []struct {
+		numRounds int
+		maxRounds int
+		expected  int
+	}{
+		{numRounds: 10, expected: http.StatusAccepted},
+		{numRounds: aliens.UntilOver, expected: http.StatusAccepted},
+		{numRounds: 1000000000, maxRounds: 5, expected: http.StatusAccepted},
+		{numRounds: 1000000000, maxRounds: aliens.NoRoundLimit, expected: http.StatusBadRequest},
+		{numRounds: aliens.UntilOver, maxRounds: aliens.NoRoundLimit, expected: http.StatusBadRequest},
+		{numRounds: 10, maxRounds: 1000000000, expected: http.StatusAccepted},
+		{numRounds: aliens.UntilOver, maxRounds: 1000000000, expected: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		code, _ := postRun(t, ts.URL, runRequest{Map: "Boston", NumAliens: 1, NumRounds: test.numRounds, MaxRounds: test.maxRounds})
+		assert.Equal(t, test.expected, code, "%+v", test)
+	}
+}
+
+func TestServeQueueFull(t *testing.T) {
+	// without workers, nothing leaves queue
+	_, ts := newTestServer(t, 0, 1)
+	code, status := postRun(t, ts.URL, runRequest{Map: "Boston"})
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, runQueued, status.Status)
+	code, _ = postRun(t, ts.URL, runRequest{Map: "Boston"})
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	code, resp := request(t, http.MethodGet, ts.URL+"/runs/"+status.ID+"/remaining", "")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "run '1' is queued\n", resp)
+}
+
+func TestServePanic(t *testing.T) {
+	s, ts := newTestServer(t, 0, 1)
+	code, status := postRun(t, ts.URL, runRequest{Map: "Boston"})
+	assert.Equal(t, http.StatusAccepted, code)
+	run := <-s.queue
+	m := aliens.NewMap()
+	m.AddCity("Boston")
+	var err error
+	run.invasion, err = aliens.NewInvasion(aliens.Options{
+		CityMap: m,
+		Events: func(aliens.Event) error {
+			panic("bad invasion")
+		},
+	})
+	assert.NoError(t, err)
+	// server carries on without the run
+	s.invade(context.Background(), run)
+	code, resp := request(t, http.MethodGet, ts.URL+"/runs/"+status.ID, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, resp, `"status": "failed"`)
+	assert.Contains(t, resp, "bad invasion")
+}
+
+func TestServeEvents(t *testing.T) {
+	// without workers, run is started by test once client is watching
+	s, ts := newTestServer(t, 0, 1)
+	code, _ := postRun(t, ts.URL, runRequest{Map: "Albany north=Boston\nBoston\nBangor north=Boston\n", Strict: true, NumAliens: 2, NumRounds: 10, Seed: 4})
+	assert.Equal(t, http.StatusAccepted, code)
+	resp, err := http.Get(ts.URL + "/runs/1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	s.invade(context.Background(), <-s.queue)
+	events, err := ioutil.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	aliens.Golden(t, *updateFlag, "testdata/serve-events.golden", bytes.NewReader(events))
+
+	// finished run only has end event
+	_, events2 := request(t, http.MethodGet, ts.URL+"/runs/1/events", "")
+	assert.True(t, strings.HasPrefix(events2, "event: end\n"), events2)
+}
+
+func TestStreamDropped(t *testing.T) {
+	s := newStream()
+	slow := s.subscribe()
+	for i := 0; i < streamBuffer+5; i++ {
+		s.publish(aliens.Event{Type: aliens.EventRound, Round: i})
+	}
+	for i := 0; i < streamBuffer; i++ {
+		e := <-slow.events
+		assert.Equal(t, 0, e.dropped)
+	}
+	// simulation went on without slow client
+	s.publish(aliens.Event{Type: aliens.EventOver})
+	e := <-slow.events
+	assert.Equal(t, 5, e.dropped)
+	assert.Equal(t, aliens.EventOver, e.Type)
+	s.close()
+	_, open := <-slow.events
+	assert.False(t, open)
+	assert.Nil(t, s.subscribe())
+}
+
+func TestStreamDroppedAtEnd(t *testing.T) {
+	s := newStream()
+	slow := s.subscribe()
+	for i := 0; i < streamBuffer+3; i++ {
+		s.publish(aliens.Event{Type: aliens.EventRound, Round: i})
+	}
+	// run finished before slow client caught up
+	s.close()
+	for range slow.events {
+	}
+	assert.Equal(t, 3, s.droppedAtEnd(slow))
+}
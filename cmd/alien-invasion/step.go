@@ -0,0 +1,493 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhubler/aliens"
+)
+
+var stepCommand = &command{
+	name:    "step",
+	args:    "[city-map-file]",
+	summary: "Step through an invasion in the terminal one landing or round at a time",
+	run:     step,
+}
+
+// most recent events shown under map
+const stepEventsShown = 10
+
+// longest invasion runs on one command before control is given back, so
+// going to the end of an invasion that might never end can be stopped
+const stepTimeLimit = 10 * time.Second
+
+var (
+	errStepPaused    = errors.New("paused")
+	errStepTimeLimit = errors.New("invasion is still going")
+)
+
+const stepHelp = `commands:
+  enter or n   next landing, or next round once aliens have landed
+  r            next round, finishing landing first
+  g N          go to round N, round 0 is when aliens have landed
+  e            go to end of invasion
+  c CITY       inspect city
+  q            quit`
+
+func step(c *cli, flags *flag.FlagSet, args []string) error {
+	numAliens := flags.Int("numAliens", aliens.DefaultNumberAliens, "Number of aliens invading")
+	numRounds := flags.Int("numRounds", aliens.DefaultInvasionRounds, "Limit the number of rounds the aliens perform before giving up or -1 to go until no more cities can be destroyed")
+	maxRounds := flags.Int("maxRounds", 0, "Most rounds any invasion can run or -1 for no limit. Default of zero for 10000")
+	seed := flags.Int64("seed", 0, "Optional random seed to control pseudo random results.  Default of zero for random each time")
+	strict := flags.Bool("strict", false, "Use a more strict parse that does not back link any cities in opposite directions")
+	recordingFile := flags.String("recording", "", "Step through invasion recorded by run -record instead of city map file and options")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	var options aliens.Options
+	var m *aliens.Map
+	var err error
+	if *recordingFile != "" {
+		in, err := os.Open(*recordingFile)
+		if err != nil {
+			return err
+		}
+		rec, err := aliens.ReadRecording(in)
+		in.Close()
+		if err != nil {
+			return err
+		}
+		options = rec.Options()
+		if m, err = aliens.ParseMap(options.CityMapInput, options.StrictMapParse); err != nil {
+			return err
+		}
+		options.CityMapInput = nil
+	} else {
+		if flags.Arg(0) == "" || flags.Arg(0) == "-" {
+			return errors.New("city map file is required, commands are read from stdin")
+		}
+		if m, err = c.readMap(flags.Arg(0), *strict); err != nil {
+			return err
+		}
+		options = aliens.Options{
+			NumberAliens:   *numAliens,
+			InvasionRounds: *numRounds,
+			MaxRounds:      *maxRounds,
+			Seed:           *seed,
+			StrictMapParse: *strict,
+		}
+		if options.Seed == 0 {
+			options.Seed = time.Now().UnixNano()
+		}
+	}
+	options.CityMap = m
+	s := newStepper(m)
+	s.seed = options.Seed
+	s.numAliens = options.NumberAliens
+	if len(options.AlienNames) > 0 {
+		s.numAliens = len(options.AlienNames)
+	}
+	options.Events = s.record
+	if s.invasion, err = aliens.NewInvasion(options); err != nil {
+		return err
+	}
+	return s.run(c)
+}
+
+// stepper runs invasion only as far as it is stepped through, pausing it
+// between landings or rounds. Events so far are kept so going back is
+// playing them again from the start.
+type stepper struct {
+	m         *aliens.Map
+	numAliens int
+	seed      int64
+	invasion  *aliens.Invasion
+	result    *aliens.Result // nil until invasion is over
+	events    []aliens.Event
+
+	// while invasion is running, event to pause it after and how to pause it
+	until     func(aliens.Event) bool
+	cancel    context.CancelCauseFunc
+	timeLimit time.Duration
+
+	// state after first shown events
+	shown     int
+	round     int
+	landed    int
+	landing   bool              // until round zero is over
+	aliens    map[string]string // roaming alien to city
+	trapped   map[string]string // trapped alien to city
+	destroyed []string
+}
+
+func newStepper(m *aliens.Map) *stepper {
+	s := &stepper{m: m, timeLimit: stepTimeLimit}
+	s.reset()
+	return s
+}
+
+func (s *stepper) reset() {
+	s.shown, s.round, s.landed, s.landing = 0, 0, 0, true
+	s.aliens = make(map[string]string)
+	s.trapped = make(map[string]string)
+	s.destroyed = nil
+}
+
+// record event of running invasion, pausing invasion once it gets as far as
+// it needs to
+func (s *stepper) record(e aliens.Event) error {
+	s.events = append(s.events, e)
+	if s.until != nil && s.until(e) {
+		s.cancel(errStepPaused)
+	}
+	return nil
+}
+
+// advance invasion until given function is true of an event, invasion is
+// over or time limit. Nil function runs invasion until it is over.
+func (s *stepper) advance(until func(aliens.Event) bool) error {
+	ctx, cancel := context.WithTimeoutCause(context.Background(), s.timeLimit, errStepTimeLimit)
+	defer cancel()
+	ctx, s.cancel = context.WithCancelCause(ctx)
+	defer s.cancel(nil)
+	s.until = until
+	result, err := s.invasion.Run(ctx)
+	s.until = nil
+	switch {
+	case err == nil:
+		s.result = result
+	case result != nil && errors.Is(err, errStepPaused):
+	case result != nil && errors.Is(err, errStepTimeLimit):
+		return errStepTimeLimit
+	default:
+		return err
+	}
+	return nil
+}
+
+// nextEvent is false once there are no more events, advancing invasion
+// with given function when every event so far has been shown
+func (s *stepper) nextEvent(until func(aliens.Event) bool) (bool, error) {
+	if s.shown == len(s.events) && s.result == nil {
+		if err := s.advance(until); err != nil {
+			return false, err
+		}
+	}
+	return s.shown < len(s.events), nil
+}
+
+func isLandingOrRound(e aliens.Event) bool {
+	return e.Type == aliens.EventLanded || e.Type == aliens.EventRound
+}
+
+func isRound(e aliens.Event) bool {
+	return e.Type == aliens.EventRound
+}
+
+func (s *stepper) apply() {
+	e := s.events[s.shown]
+	s.shown++
+	s.round = e.Round
+	switch e.Type {
+	case aliens.EventLanded:
+		s.landed++
+		s.aliens[e.Alien] = e.City
+	case aliens.EventMoved:
+		s.aliens[e.Alien] = e.City
+	case aliens.EventTrapped:
+		delete(s.aliens, e.Alien)
+		s.trapped[e.Alien] = e.City
+	case aliens.EventDestroyed:
+		delete(s.aliens, e.Alien)
+		delete(s.aliens, e.OtherAlien)
+		s.destroyed = append(s.destroyed, e.City)
+	case aliens.EventRound:
+		s.landing = false
+	}
+}
+
+func (s *stepper) over() bool {
+	return s.shown == len(s.events) && s.result != nil
+}
+
+// next landing along with the city it destroys, if it does. Invasion is
+// paused between landings so the city destroyed is already an event.
+func (s *stepper) nextLanding() error {
+	if more, err := s.nextEvent(isLandingOrRound); !more {
+		return err
+	}
+	s.apply()
+	for s.shown < len(s.events) && s.events[s.shown].Type == aliens.EventDestroyed {
+		s.apply()
+	}
+	return nil
+}
+
+// nextRound plays events up to end of round, including landing, advancing
+// invasion with given function when needed
+func (s *stepper) nextRound(until func(aliens.Event) bool) error {
+	for {
+		more, err := s.nextEvent(until)
+		if !more {
+			return err
+		}
+		s.apply()
+		if e := s.events[s.shown-1]; e.Type == aliens.EventRound {
+			break
+		}
+	}
+	// invasion over comes right after last round
+	if s.shown < len(s.events) && s.events[s.shown].Type == aliens.EventOver {
+		s.apply()
+	}
+	return nil
+}
+
+// goTo end of given round
+func (s *stepper) goTo(round int) error {
+	s.reset()
+	until := func(e aliens.Event) bool {
+		return e.Type == aliens.EventRound && e.Round >= round
+	}
+	for !s.over() {
+		if err := s.nextRound(until); err != nil {
+			return err
+		}
+		if s.round >= round {
+			break
+		}
+	}
+	return nil
+}
+
+// toEnd of invasion, running it until it is over
+func (s *stepper) toEnd() error {
+	for !s.over() {
+		if err := s.nextRound(nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *stepper) run(c *cli) error {
+	in := bufio.NewScanner(c.stdin)
+	message := ""
+	for {
+		s.draw(c, message)
+		message = ""
+		if !in.Scan() {
+			return in.Err()
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(in.Text()), " ")
+		arg = strings.TrimSpace(arg)
+		var err error
+		switch cmd {
+		case "", "n":
+			switch {
+			case s.over():
+				message = "invasion is over"
+			case s.landing:
+				err = s.nextLanding()
+			default:
+				err = s.nextRound(isRound)
+			}
+		case "r":
+			if s.over() {
+				message = "invasion is over"
+			} else {
+				err = s.nextRound(isRound)
+			}
+		case "g":
+			round, convErr := strconv.Atoi(arg)
+			if convErr != nil || round < 0 {
+				message = fmt.Sprintf("round '%s' is not a number of 0 or more", arg)
+				break
+			}
+			err = s.goTo(round)
+			if err == nil && s.round < round {
+				message = fmt.Sprintf("invasion was over after round %d", s.round)
+			}
+		case "e":
+			err = s.toEnd()
+		case "c":
+			message = s.inspect(arg)
+		case "q":
+			return nil
+		case "h", "?":
+			message = stepHelp
+		default:
+			message = fmt.Sprintf("unrecognized command '%s'\n%s", cmd, stepHelp)
+		}
+		if errors.Is(err, errStepTimeLimit) {
+			message = fmt.Sprintf("%s after %s, e to keep going", err, s.timeLimit)
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// draw screen, clearing terminal first if there is one
+func (s *stepper) draw(c *cli, message string) {
+	if f, isFile := c.stdout.(*os.File); isFile {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			io.WriteString(c.stdout, "\033[H\033[2J")
+		}
+	}
+	var b strings.Builder
+	status := fmt.Sprintf("landing %d of %d aliens", s.landed, s.numAliens)
+	if !s.landing {
+		status = fmt.Sprintf("round %d", s.round)
+		if s.result != nil {
+			status += fmt.Sprintf(" of %d", s.result.Rounds)
+		}
+	}
+	if s.over() {
+		status += fmt.Sprintf(", invasion over, ended %s", s.result.Ended)
+	}
+	fmt.Fprintf(&b, "seed %d, %s\n", s.seed, status)
+	b.WriteString("--- map\n")
+	s.roundMap().Write(&b)
+	b.WriteString("--- events\n")
+	first := s.shown - stepEventsShown
+	if first < 0 {
+		first = 0
+	}
+	for _, e := range s.events[first:s.shown] {
+		if text := describeEvent(e); text != "" {
+			fmt.Fprintf(&b, "%d: %s\n", e.Round, text)
+		}
+	}
+	if message != "" {
+		fmt.Fprintf(&b, "---\n%s\n", message)
+	}
+	b.WriteString("[enter] next, r round, g N go to round, e end, c CITY inspect, q quit, h help > ")
+	io.WriteString(c.stdout, b.String())
+}
+
+// roundMap is what is left of map and where aliens are at this point
+func (s *stepper) roundMap() *aliens.RoundMap {
+	m := &aliens.RoundMap{
+		Round:     s.round,
+		Cities:    make(map[string]map[string]string),
+		Aliens:    make(map[string]string),
+		Trapped:   make(map[string]string),
+		Destroyed: s.destroyed,
+	}
+	destroyed := s.destroyedCities()
+	for _, name := range s.m.CityNames() {
+		if !destroyed[name] {
+			m.Cities[name] = s.roads(name, destroyed)
+		}
+	}
+	for a, city := range s.aliens {
+		m.Aliens[city] = a
+	}
+	for a, city := range s.trapped {
+		m.Trapped[city] = a
+	}
+	return m
+}
+
+func (s *stepper) destroyedCities() map[string]bool {
+	destroyed := make(map[string]bool, len(s.destroyed))
+	for _, name := range s.destroyed {
+		destroyed[name] = true
+	}
+	return destroyed
+}
+
+// roads out of city that are left
+func (s *stepper) roads(name string, destroyed map[string]bool) map[string]string {
+	roads := make(map[string]string)
+	for _, d := range compass {
+		if neighbor := s.m.Neighbor(name, d.direction); neighbor != "" && !destroyed[neighbor] {
+			roads[d.label] = neighbor
+		}
+	}
+	return roads
+}
+
+// inspect city's roads and who is in it
+func (s *stepper) inspect(name string) string {
+	if name == "" {
+		return "city name is required like c Boston"
+	}
+	found := false
+	for _, candidate := range s.m.CityNames() {
+		if candidate == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Sprintf("no city '%s'", name)
+	}
+	var b strings.Builder
+	destroyed := s.destroyedCities()
+	if destroyed[name] {
+		for _, e := range s.events[:s.shown] {
+			if e.Type == aliens.EventDestroyed && e.City == name {
+				fmt.Fprintf(&b, "%s was destroyed in round %d by alien %s and alien %s\n", name, e.Round, e.Alien, e.OtherAlien)
+			}
+		}
+	} else {
+		fmt.Fprintf(&b, "%s is standing\n", name)
+	}
+	remaining := s.roads(name, destroyed)
+	for _, d := range compass {
+		neighbor := s.m.Neighbor(name, d.direction)
+		if neighbor == "" {
+			continue
+		}
+		if _, left := remaining[d.label]; left && !destroyed[name] {
+			fmt.Fprintf(&b, "  %s=%s\n", d.label, neighbor)
+		} else {
+			fmt.Fprintf(&b, "  %s=%s severed\n", d.label, neighbor)
+		}
+	}
+	var occupants []string
+	for a, city := range s.aliens {
+		if city == name {
+			occupants = append(occupants, "alien "+a)
+		}
+	}
+	for a, city := range s.trapped {
+		if city == name {
+			occupants = append(occupants, "alien "+a+" trapped")
+		}
+	}
+	sort.Strings(occupants)
+	if len(occupants) == 0 {
+		b.WriteString("no aliens")
+	} else {
+		b.WriteString(strings.Join(occupants, ", "))
+	}
+	return b.String()
+}
+
+// describeEvent in words or empty for events that are not worth showing
+func describeEvent(e aliens.Event) string {
+	switch e.Type {
+	case aliens.EventLanded:
+		return fmt.Sprintf("alien %s landed in %s", e.Alien, e.City)
+	case aliens.EventMoved:
+		return fmt.Sprintf("alien %s moved from %s to %s", e.Alien, e.From, e.City)
+	case aliens.EventDestroyed:
+		return aliens.FallenCity{City: e.City, Aliens: [2]string{e.Alien, e.OtherAlien}}.String()
+	case aliens.EventTrapped:
+		return fmt.Sprintf("alien %s is trapped in %s", e.Alien, e.City)
+	case aliens.EventOver:
+		return fmt.Sprintf("invasion over, ended %s", e.Ended)
+	}
+	return ""
+}
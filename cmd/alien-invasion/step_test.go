@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dhubler/aliens"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepTimeLimit(t *testing.T) {
+	m, err := aliens.ParseMapFile("../../testdata/small-map.txt", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newStepper(m)
+	s.invasion, err = aliens.NewInvasion(aliens.Options{
+		NumberAliens:   4,
+		InvasionRounds: aliens.UntilOver,
+		MaxRounds:      aliens.NoRoundLimit,
+		Seed:           20,
+		CityMap:        m,
+		Events:         s.record,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.timeLimit = time.Nanosecond
+	assert.ErrorIs(t, s.toEnd(), errStepTimeLimit)
+	assert.False(t, s.over())
+
+	// picks up where it was stopped
+	s.timeLimit = time.Minute
+	assert.NoError(t, s.toEnd())
+	assert.True(t, s.over())
+	assert.Equal(t, s.result.Rounds, s.round)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/dhubler/aliens"
+)
+
+// events a client can fall behind by before events are dropped for that
+// client
+const streamBuffer = 1000
+
+// stream hands events of a run to clients watching it. Invasion never waits
+// on clients, events are dropped for clients that fall too far behind.
+type stream struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]bool
+	closed      bool
+}
+
+type subscriber struct {
+	events  chan streamEvent
+	dropped int // since last event client was given, guarded by stream mutex
+}
+
+// streamEvent is an event along with how many events were dropped right
+// before it
+type streamEvent struct {
+	aliens.Event
+	dropped int
+}
+
+func newStream() *stream {
+	return &stream{subscribers: make(map[*subscriber]bool)}
+}
+
+// publish is given to invasion as Options.Events
+func (s *stream) publish(e aliens.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers {
+		select {
+		case sub.events <- streamEvent{Event: e, dropped: sub.dropped}:
+			sub.dropped = 0
+		default:
+			sub.dropped++
+		}
+	}
+	return nil
+}
+
+// subscribe is nil when run is already finished
+func (s *stream) subscribe() *subscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	sub := &subscriber{events: make(chan streamEvent, streamBuffer)}
+	s.subscribers[sub] = true
+	return sub
+}
+
+func (s *stream) unsubscribe(sub *subscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscribers, sub)
+}
+
+// droppedAtEnd is how many events client missed after the last one it was
+// given, only known once stream is closed as there is no next event to tell
+func (s *stream) droppedAtEnd(sub *subscriber) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sub.dropped
+}
+
+// close lets clients know run is finished
+func (s *stream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for sub := range s.subscribers {
+		close(sub.events)
+	}
+	s.subscribers = nil
+}
+
+// watch streams events of a run as Server-Sent Events until run is finished,
+// client goes away or server is stopped. Last event is end with status of run.
+func (s *server) watch(w http.ResponseWriter, r *http.Request, run *serverRun) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	// subscribed before client gets response so no events are missed
+	sub := run.stream.subscribe()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	if sub != nil {
+		defer run.stream.unsubscribe(sub)
+	events:
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-s.done:
+				return
+			case e, open := <-sub.events:
+				if !open {
+					if dropped := run.stream.droppedAtEnd(sub); dropped > 0 {
+						writeEvent(w, "dropped", map[string]int{"dropped": dropped})
+					}
+					break events
+				}
+				if e.dropped > 0 {
+					writeEvent(w, "dropped", map[string]int{"dropped": e.dropped})
+				}
+				writeEvent(w, e.Type, e.Event)
+				flusher.Flush()
+			}
+		}
+	}
+	s.mu.Lock()
+	status := run.statusLocked()
+	s.mu.Unlock()
+	writeEvent(w, "end", status)
+	flusher.Flush()
+}
+
+// writeEvent in Server-Sent Events format with data as a single line of JSON
+func writeEvent(w http.ResponseWriter, event string, data interface{}) {
+	content, _ := json.Marshal(data)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, content)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+var validateCommand = &command{
+	name:    "validate",
+	args:    "[city-map-file ...]",
+	summary: "Check city map files for errors",
+	run:     validate,
+}
+
+func validate(c *cli, flags *flag.FlagSet, args []string) error {
+	strict := flags.Bool("strict", false, "Use a more strict parse that does not back link any cities in opposite directions")
+	if err := c.parse(flags, args, -1); err != nil {
+		return err
+	}
+	fnames := flags.Args()
+	if len(fnames) == 0 {
+		fnames = []string{"-"}
+	}
+	invalid := 0
+	for _, fname := range fnames {
+		if err := validateFile(c, fname, *strict); err != nil {
+			// errors from map files already say which file
+			if fname == "-" {
+				fmt.Fprintf(c.stderr, "%s: %s\n", fname, err)
+			} else {
+				fmt.Fprintln(c.stderr, err)
+			}
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d city map(s) are invalid", invalid, len(fnames))
+	}
+	return nil
+}
+
+func validateFile(c *cli, fname string, strict bool) error {
+	_, err := c.readMap(fname, strict)
+	return err
+}
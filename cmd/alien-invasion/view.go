@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/dhubler/aliens"
+)
+
+var viewCommand = &command{
+	name:    "view",
+	args:    "[recording-file]",
+	summary: "Watch an invasion recorded by run -record in a web browser",
+	run:     view,
+}
+
+//go:embed viewer
+var viewerFiles embed.FS
+
+func view(c *cli, flags *flag.FlagSet, args []string) error {
+	addr := flags.String("addr", "localhost:8081", "Address to listen on")
+	if err := c.parse(flags, args, 1); err != nil {
+		return err
+	}
+	in, err := c.open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	rec, err := aliens.ReadRecording(in)
+	in.Close()
+	if err != nil {
+		return err
+	}
+	viewer, err := newViewer(rec)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	httpServer := &http.Server{Handler: viewer}
+	go func() {
+		<-ctx.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+	fmt.Fprintf(c.stderr, "open http://%s in a web browser, Ctrl-C to stop\n", listener.Addr())
+	if err = httpServer.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// viewInvasion is everything viewer page needs to play back an invasion
+type viewInvasion struct {
+	Seed   int64          `json:"seed"`
+	Rounds int            `json:"rounds"`
+	Ended  string         `json:"ended"`
+	Cities []viewCity     `json:"cities"`
+	Events []aliens.Event `json:"events"`
+}
+
+// viewCity is a city at a spot on a grid and its roads before invasion
+type viewCity struct {
+	Name  string            `json:"name"`
+	X     int               `json:"x"`
+	Y     int               `json:"y"`
+	Roads map[string]string `json:"roads"`
+}
+
+// newViewer replays invasion and serves viewer page along with the replay
+// at /invasion.json
+func newViewer(rec *aliens.Recording) (http.Handler, error) {
+	options := rec.Options()
+	m, err := aliens.ParseMap(options.CityMapInput, options.StrictMapParse)
+	if err != nil {
+		return nil, err
+	}
+	options.CityMapInput = nil
+	options.CityMap = m
+	invasion := &viewInvasion{Cities: layout(m)}
+	options.Events = func(e aliens.Event) error {
+		invasion.Events = append(invasion.Events, e)
+		return nil
+	}
+	result, err := aliens.Invade(options)
+	if err != nil {
+		return nil, err
+	}
+	invasion.Seed, invasion.Rounds, invasion.Ended = result.Seed, result.Rounds, result.Ended
+	data, err := json.Marshal(invasion)
+	if err != nil {
+		return nil, err
+	}
+	files, err := fs.Sub(viewerFiles, "viewer")
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(files)))
+	mux.HandleFunc("/invasion.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+	return mux, nil
+}
+
+// directions with grid steps, y grows going south like on a screen. Up and
+// down are drawn on a slant.
+var compass = []struct {
+	direction int
+	label     string
+	dx, dy    int
+}{
+	{aliens.North, "north", 0, -1},
+	{aliens.East, "east", 1, 0},
+	{aliens.South, "south", 0, 1},
+	{aliens.West, "west", -1, 0},
+	{aliens.Up, "up", 1, -1},
+	{aliens.Down, "down", -1, 1},
+}
+
+// layout puts cities on a grid so roads go in their compass direction where
+// possible. Cities that would land on a spot already taken go to the nearest
+// free spot. Each group of connected cities is to the right of the last.
+func layout(m *aliens.Map) []viewCity {
+	names := m.CityNames()
+	spots := make(map[string][2]int, len(names))
+	// cities with roads into a city but none back are placed near it too
+	into := make(map[string][]string)
+	for _, name := range names {
+		for _, d := range compass {
+			if neighbor := m.Neighbor(name, d.direction); neighbor != "" {
+				into[neighbor] = append(into[neighbor], name)
+			}
+		}
+	}
+	right := 0 // first free column right of groups placed so far
+	for _, start := range names {
+		if _, placed := spots[start]; placed {
+			continue
+		}
+		group := []string{start}
+		taken := map[[2]int]bool{{0, 0}: true}
+		spots[start] = [2]int{0, 0}
+		place := func(name string, spot [2]int) {
+			if taken[spot] {
+				spot = nearestFree(taken, spot)
+			}
+			spots[name] = spot
+			taken[spot] = true
+			group = append(group, name)
+		}
+		for next := 0; next < len(group); next++ {
+			name := group[next]
+			for _, d := range compass {
+				neighbor := m.Neighbor(name, d.direction)
+				if neighbor == "" {
+					continue
+				}
+				if _, placed := spots[neighbor]; !placed {
+					place(neighbor, [2]int{spots[name][0] + d.dx, spots[name][1] + d.dy})
+				}
+			}
+			for _, other := range into[name] {
+				if _, placed := spots[other]; !placed {
+					place(other, spots[name])
+				}
+			}
+		}
+		// shift group so it starts at top and right of last group
+		minX, minY, maxX := 0, 0, 0
+		for _, name := range group {
+			spot := spots[name]
+			minX, minY, maxX = min(minX, spot[0]), min(minY, spot[1]), max(maxX, spot[0])
+		}
+		for _, name := range group {
+			spots[name] = [2]int{spots[name][0] - minX + right, spots[name][1] - minY}
+		}
+		right += maxX - minX + 2
+	}
+	cities := make([]viewCity, 0, len(names))
+	for _, name := range names {
+		roads := make(map[string]string)
+		for _, d := range compass {
+			if neighbor := m.Neighbor(name, d.direction); neighbor != "" {
+				roads[d.label] = neighbor
+			}
+		}
+		cities = append(cities, viewCity{
+			Name:  name,
+			X:     spots[name][0],
+			Y:     spots[name][1],
+			Roads: roads,
+		})
+	}
+	return cities
+}
+
+// nearestFree is the closest spot to given spot that is not taken, searching
+// in growing squares around it
+func nearestFree(taken map[[2]int]bool, spot [2]int) [2]int {
+	for r := 1; ; r++ {
+		for dy := -r; dy <= r; dy++ {
+			for dx := -r; dx <= r; dx++ {
+				if dx != -r && dx != r && dy != -r && dy != r {
+					continue
+				}
+				candidate := [2]int{spot[0] + dx, spot[1] + dy}
+				if !taken[candidate] {
+					return candidate
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dhubler/aliens"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayout(t *testing.T) {
+	m, err := aliens.ParseMap(strings.NewReader("Boston north=Bangor south=NewYork west=Albany\nNewYork south=Trenton west=Columbus\nParis\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spots := make(map[string][2]int)
+	for _, c := range layout(m) {
+		spots[c.Name] = [2]int{c.X, c.Y}
+	}
+	assert.Equal(t, map[string][2]int{
+		"Albany":   {0, 1},
+		"Bangor":   {1, 0},
+		"Boston":   {1, 1},
+		"Columbus": {0, 2},
+		"NewYork":  {1, 2},
+		"Trenton":  {1, 3},
+		"Paris":    {3, 0},
+	}, spots)
+
+	// roads that do not agree with each other still get their own spot
+	m, err = aliens.ParseMap(strings.NewReader("A east=B south=C\nB south=D\nC east=E\nD\nE\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken := make(map[[2]int]string)
+	for _, c := range layout(m) {
+		spot := [2]int{c.X, c.Y}
+		assert.Empty(t, taken[spot], "%s and %s", taken[spot], c.Name)
+		taken[spot] = c.Name
+	}
+}
+
+func TestViewer(t *testing.T) {
+	smallMap, err := ioutil.ReadFile("../../testdata/small-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := aliens.NewRecording(aliens.Options{NumberAliens: 10, InvasionRounds: 3, Seed: 20}, string(smallMap))
+	viewer, err := newViewer(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(viewer)
+	defer ts.Close()
+
+	code, page := request(t, http.MethodGet, ts.URL+"/", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, page, `<script src="viewer.js">`)
+	for _, asset := range []string{"/viewer.js", "/viewer.css"} {
+		code, _ = request(t, http.MethodGet, ts.URL+asset, "")
+		assert.Equal(t, http.StatusOK, code, asset)
+	}
+
+	code, data := request(t, http.MethodGet, ts.URL+"/invasion.json", "")
+	assert.Equal(t, http.StatusOK, code)
+	var invasion viewInvasion
+	assert.NoError(t, json.Unmarshal([]byte(data), &invasion))
+	assert.Equal(t, 3, invasion.Rounds)
+	assert.Equal(t, 6, len(invasion.Cities))
+	destroyed := 0
+	for _, e := range invasion.Events {
+		if e.Type == aliens.EventDestroyed {
+			destroyed++
+		}
+	}
+	assert.Equal(t, 4, destroyed)
+}
@@ -0,0 +1,200 @@
+// plays back an invasion from /invasion.json one round at a time. state of
+// any round is found by applying events from the start so stepping back is
+// as easy as stepping forward.
+(function () {
+  const spacing = 90;
+  const margin = 50;
+  const svgNS = "http://www.w3.org/2000/svg";
+
+  const svg = document.getElementById("map");
+  const roundLabel = document.getElementById("round");
+  const log = document.getElementById("log");
+  const playButton = document.getElementById("play");
+
+  let invasion;
+  let rounds = []; // events of each round, round zero is landing
+  let current = -1; // last round shown
+  let timer = null;
+  const cities = {};
+  const roads = [];
+  const alienShapes = {};
+
+  function el(name, attrs, parent) {
+    const e = document.createElementNS(svgNS, name);
+    for (const [k, v] of Object.entries(attrs)) {
+      e.setAttribute(k, v);
+    }
+    parent.appendChild(e);
+    return e;
+  }
+
+  function position(city) {
+    return [margin + city.x * spacing, margin + city.y * spacing];
+  }
+
+  function drawMap() {
+    let width = 0;
+    let height = 0;
+    for (const city of invasion.cities) {
+      cities[city.name] = city;
+      width = Math.max(width, city.x);
+      height = Math.max(height, city.y);
+    }
+    svg.setAttribute("viewBox", `0 0 ${2 * margin + width * spacing} ${2 * margin + height * spacing}`);
+    const roadLayer = el("g", {}, svg);
+    const cityLayer = el("g", {}, svg);
+    el("g", { id: "aliens" }, svg);
+    for (const city of invasion.cities) {
+      const [x1, y1] = position(city);
+      for (const to of Object.values(city.roads)) {
+        const [x2, y2] = position(cities[to]);
+        const line = el("line", { class: "road", x1: x1, y1: y1, x2: x2, y2: y2 }, roadLayer);
+        roads.push({ from: city.name, to: to, line: line });
+      }
+      const g = el("g", { class: "city", transform: `translate(${x1},${y1})` }, cityLayer);
+      el("circle", { r: 22 }, g);
+      el("text", { y: 36 }, g).textContent = city.name;
+      city.shape = g;
+    }
+  }
+
+  function describe(e) {
+    switch (e.type) {
+      case "landed":
+        return `alien ${e.alien} landed in ${e.city}`;
+      case "moved":
+        return `alien ${e.alien} moved from ${e.from} to ${e.city}`;
+      case "destroyed":
+        return `${e.city} has been destroyed by alien ${e.alien} and alien ${e.otherAlien}!`;
+      case "trapped":
+        return `alien ${e.alien} is trapped in ${e.city}`;
+      case "over":
+        return `invasion over, ended ${e.ended}`;
+    }
+    return "";
+  }
+
+  // state after given round
+  function stateAt(round) {
+    const state = { aliens: {}, trapped: {}, killed: {}, destroyed: {} };
+    for (let r = 0; r <= round; r++) {
+      for (const e of rounds[r]) {
+        switch (e.type) {
+          case "landed":
+          case "moved":
+            state.aliens[e.alien] = e.city;
+            break;
+          case "trapped":
+            state.trapped[e.alien] = true;
+            break;
+          case "destroyed":
+            state.destroyed[e.city] = true;
+            state.killed[e.alien] = true;
+            state.killed[e.otherAlien] = true;
+            break;
+        }
+      }
+    }
+    return state;
+  }
+
+  function show(round) {
+    current = round;
+    const state = stateAt(round);
+    for (const city of invasion.cities) {
+      city.shape.classList.toggle("destroyed", !!state.destroyed[city.name]);
+    }
+    for (const road of roads) {
+      road.line.classList.toggle("severed", !!(state.destroyed[road.from] || state.destroyed[road.to]));
+    }
+    // aliens in same city are spread out a little
+    const occupants = {};
+    const layer = document.getElementById("aliens");
+    for (const [alien, cityName] of Object.entries(state.aliens)) {
+      const n = (occupants[cityName] = (occupants[cityName] || 0) + 1) - 1;
+      const [x, y] = position(cities[cityName]);
+      let shape = alienShapes[alien];
+      if (!shape) {
+        shape = el("g", { class: "alien" }, layer);
+        el("circle", { r: 8 }, shape);
+        el("text", {}, shape).textContent = alien;
+        alienShapes[alien] = shape;
+      }
+      shape.style.transform = `translate(${x - 8 + n * 16}px, ${y - 8}px)`;
+      shape.classList.toggle("trapped", !!state.trapped[alien]);
+      shape.classList.toggle("killed", !!state.killed[alien]);
+    }
+    for (const [alien, shape] of Object.entries(alienShapes)) {
+      shape.style.display = alien in state.aliens ? "" : "none";
+    }
+    const last = rounds.length - 1;
+    roundLabel.textContent = round === 0 ? `landing of ${last} round(s)` : `round ${round} of ${last}`;
+
+    log.replaceChildren();
+    for (let r = 0; r <= round; r++) {
+      for (const e of rounds[r]) {
+        const text = describe(e);
+        if (text) {
+          const li = document.createElement("li");
+          li.textContent = `${r}: ${text}`;
+          li.classList.toggle("destroyed", e.type === "destroyed");
+          li.classList.toggle("current", r === round);
+          log.appendChild(li);
+        }
+      }
+    }
+    log.scrollTop = log.scrollHeight;
+  }
+
+  function step(by) {
+    const next = Math.min(Math.max(current + by, 0), rounds.length - 1);
+    if (next !== current) {
+      show(next);
+    }
+    if (next === rounds.length - 1) {
+      pause();
+    }
+  }
+
+  function play() {
+    if (current === rounds.length - 1) {
+      show(0);
+    }
+    const speed = Number(document.getElementById("speed").value);
+    timer = setInterval(() => step(1), 2000 / speed);
+    playButton.innerHTML = "&#x23F8;";
+  }
+
+  function pause() {
+    clearInterval(timer);
+    timer = null;
+    playButton.innerHTML = "&#x23F5;";
+  }
+
+  playButton.onclick = () => (timer ? pause() : play());
+  document.getElementById("step").onclick = () => { pause(); step(1); };
+  document.getElementById("back").onclick = () => { pause(); step(-1); };
+  document.getElementById("restart").onclick = () => { pause(); show(0); };
+  document.getElementById("speed").onchange = () => { if (timer) { pause(); play(); } };
+  document.addEventListener("keydown", (e) => {
+    if (e.key === " ") { playButton.onclick(); e.preventDefault(); }
+    if (e.key === "ArrowRight") { pause(); step(1); }
+    if (e.key === "ArrowLeft") { pause(); step(-1); }
+  });
+
+  fetch("invasion.json")
+    .then((resp) => resp.json())
+    .then((data) => {
+      invasion = data;
+      for (let r = 0; r <= invasion.rounds; r++) {
+        rounds.push([]);
+      }
+      for (const e of invasion.events || []) {
+        rounds[e.round].push(e);
+      }
+      document.getElementById("summary").textContent =
+        `seed ${invasion.seed}, ${invasion.rounds} round(s), ${invasion.cities.length} cities, ended ${invasion.ended}`;
+      drawMap();
+      show(0);
+    });
+})();
@@ -0,0 +1,108 @@
+package aliens
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MapDiff is how one map differs from another. Roads out of cities that were
+// added or removed are not listed, they go along with the city. Roads into
+// them from other cities are.
+type MapDiff struct {
+	RemovedCities []string      `json:"removedCities"`
+	AddedCities   []string      `json:"addedCities"`
+	RemovedRoads  []Road        `json:"removedRoads"`
+	AddedRoads    []Road        `json:"addedRoads"`
+	ChangedRoads  []ChangedRoad `json:"changedRoads"`
+}
+
+// ChangedRoad goes in the same direction out of a city but to another city
+type ChangedRoad struct {
+	From      string `json:"from"`
+	Direction string `json:"direction"`
+	OldTo     string `json:"oldTo"`
+	NewTo     string `json:"newTo"`
+}
+
+// DiffMaps finds what changed going from before map to after map, like a map given
+// to an invasion and the remaining cities report of it. Everything is in city
+// name then direction order.
+func DiffMaps(before, after *Map) *MapDiff {
+	d := &MapDiff{
+		RemovedCities: []string{},
+		AddedCities:   []string{},
+		RemovedRoads:  []Road{},
+		AddedRoads:    []Road{},
+		ChangedRoads:  []ChangedRoad{},
+	}
+	for _, name := range before.CityNames() {
+		if _, exists := after.cities[name]; !exists {
+			d.RemovedCities = append(d.RemovedCities, name)
+		}
+	}
+	for _, name := range after.CityNames() {
+		if _, exists := before.cities[name]; !exists {
+			d.AddedCities = append(d.AddedCities, name)
+		}
+	}
+	for _, name := range before.CityNames() {
+		if _, exists := after.cities[name]; !exists {
+			continue
+		}
+		for direction, label := range directionLabels {
+			was, now := before.Neighbor(name, direction), after.Neighbor(name, direction)
+			switch {
+			case was == now:
+			case now == "":
+				d.RemovedRoads = append(d.RemovedRoads, Road{From: name, Direction: label, To: was})
+			case was == "":
+				d.AddedRoads = append(d.AddedRoads, Road{From: name, Direction: label, To: now})
+			default:
+				d.ChangedRoads = append(d.ChangedRoads, ChangedRoad{From: name, Direction: label, OldTo: was, NewTo: now})
+			}
+		}
+	}
+	return d
+}
+
+// Same is true when maps have the same cities and roads
+func (d *MapDiff) Same() bool {
+	return len(d.RemovedCities) == 0 && len(d.AddedCities) == 0 &&
+		len(d.RemovedRoads) == 0 && len(d.AddedRoads) == 0 && len(d.ChangedRoads) == 0
+}
+
+// Write differences a line each, - for removed, + for added and ~ for
+// changed. Cities and roads are in the same format as city map files.
+// Example:
+//   - Bangor
+//   + Boston east=Salem
+//   ~ Boston west=Albany -> Worcester
+func (d *MapDiff) Write(w io.Writer) error {
+	var b strings.Builder
+	for _, name := range d.RemovedCities {
+		fmt.Fprintf(&b, "- %s\n", quoteName(name))
+	}
+	for _, name := range d.AddedCities {
+		fmt.Fprintf(&b, "+ %s\n", quoteName(name))
+	}
+	for _, road := range d.RemovedRoads {
+		fmt.Fprintf(&b, "- %s %s=%s\n", quoteName(road.From), road.Direction, quoteName(road.To))
+	}
+	for _, road := range d.AddedRoads {
+		fmt.Fprintf(&b, "+ %s %s=%s\n", quoteName(road.From), road.Direction, quoteName(road.To))
+	}
+	for _, road := range d.ChangedRoads {
+		fmt.Fprintf(&b, "~ %s %s=%s -> %s\n", quoteName(road.From), road.Direction, quoteName(road.OldTo), quoteName(road.NewTo))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes differences as JSON document
+func (d *MapDiff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
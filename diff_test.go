@@ -0,0 +1,39 @@
+package aliens
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffMaps(t *testing.T) {
+	before, err := ParseMap(strings.NewReader("Boston north=Bangor west=Albany south=NewYork\n\"New Haven\" north=NewYork\n"), true)
+	assert.NoError(t, err)
+	after, err := ParseMap(strings.NewReader("Boston north=Bangor west=Worcester east=Salem\n\"New Haven\"\nWorcester east=Boston\n"), true)
+	assert.NoError(t, err)
+
+	d := DiffMaps(before, after)
+	assert.False(t, d.Same())
+	assert.Equal(t, []string{"Albany", "NewYork"}, d.RemovedCities)
+	assert.Equal(t, []string{"Salem", "Worcester"}, d.AddedCities)
+	var buf bytes.Buffer
+	assert.NoError(t, d.Write(&buf))
+	expected := `- Albany
+- NewYork
++ Salem
++ Worcester
+- Boston south=NewYork
+- "New Haven" north=NewYork
++ Boston east=Salem
+~ Boston west=Albany -> Worcester
+`
+	assert.Equal(t, expected, buf.String())
+
+	same := DiffMaps(after, after)
+	assert.True(t, same.Same())
+	buf.Reset()
+	assert.NoError(t, same.WriteJSON(&buf))
+	assert.Equal(t, "{\n  \"removedCities\": [],\n  \"addedCities\": [],\n  \"removedRoads\": [],\n  \"addedRoads\": [],\n  \"changedRoads\": []\n}\n", buf.String())
+}
@@ -0,0 +1,10 @@
+// Package aliens simulates aliens invading a map of cities and reports back
+// the remaining cities. See README.md for the rules aliens follow.
+//
+// Invasions share no state with each other so any number of them can run in
+// parallel goroutines, each with its own Options.  A Map given in
+// Options.CityMap is copied by every invasion and can be shared as long as it
+// is not changed while invasions are starting.  Readers, writers and loggers
+// given in Options are used as is and should only be shared between invasions
+// running in parallel if they are safe for concurrent use.
+package aliens
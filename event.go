@@ -0,0 +1,33 @@
+package aliens
+
+// kinds of Event
+const (
+	EventLanded    = "landed"    // alien landed in city
+	EventMoved     = "moved"     // alien moved from one city to another
+	EventDestroyed = "destroyed" // city was destroyed by alien and other alien
+	EventTrapped   = "trapped"   // alien found no roads out of city
+	EventRound     = "round"     // round is complete, round zero is landing
+	EventOver      = "over"      // invasion is over
+)
+
+// Event is something that happened during an invasion, given to
+// Options.Events as it happens. Only fields that apply to the kind of event
+// are set.
+type Event struct {
+	Type       string `json:"type"`
+	Round      int    `json:"round"`
+	Alien      string `json:"alien,omitempty"`
+	OtherAlien string `json:"otherAlien,omitempty"`
+	From       string `json:"from,omitempty"`
+	City       string `json:"city,omitempty"`
+	Ended      string `json:"ended,omitempty"` // how invasion ended when over
+}
+
+// emit event to Options.Events in the round it happened in
+func (sim *Invasion) emit(e Event) error {
+	if sim.events == nil {
+		return nil
+	}
+	e.Round = sim.roundsCompleted
+	return sim.events(e)
+}
@@ -0,0 +1,79 @@
+package aliens
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	tests := []struct {
+		cityMap  string
+		aliens   []string
+		numbers  []int
+		expected []Event
+	}{
+		{
+			// x lands in Albany and y in Bangor then both move to Boston
+			cityMap: "Albany north=Boston\nBoston\nBangor north=Boston\n",
+			aliens:  []string{"x", "y"},
+			numbers: []int{0, 1, 0, 0},
+			expected: []Event{
+				{Type: EventLanded, Alien: "x", City: "Albany"},
+				{Type: EventLanded, Alien: "y", City: "Bangor"},
+				{Type: EventRound},
+				{Type: EventMoved, Round: 1, Alien: "x", From: "Albany", City: "Boston"},
+				{Type: EventMoved, Round: 1, Alien: "y", From: "Bangor", City: "Boston"},
+				{Type: EventDestroyed, Round: 1, Alien: "y", OtherAlien: "x", City: "Boston"},
+				{Type: EventRound, Round: 1},
+				{Type: EventOver, Round: 1, Ended: EndedNoAliens},
+			},
+		},
+		{
+			// x lands in Bangor which has no roads out
+			cityMap: "Boston north=Bangor\nBangor\n",
+			aliens:  []string{"x"},
+			numbers: []int{0, 0},
+			expected: []Event{
+				{Type: EventLanded, Alien: "x", City: "Bangor"},
+				{Type: EventRound},
+				{Type: EventTrapped, Round: 1, Alien: "x", City: "Bangor"},
+				{Type: EventRound, Round: 1},
+				{Type: EventOver, Round: 1, Ended: EndedNoAliens},
+			},
+		},
+	}
+	for _, test := range tests {
+		var events []Event
+		_, err := Invade(Options{
+			AlienNames:     test.aliens,
+			InvasionRounds: 10,
+			CityMapInput:   strings.NewReader(test.cityMap),
+			StrictMapParse: true,
+			Random:         &ScriptedRandom{Numbers: test.numbers},
+			Events: func(e Event) error {
+				events = append(events, e)
+				return nil
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, events)
+	}
+
+	// error stops invasion
+	stop := errors.New("stop")
+	_, err := Invade(Options{
+		NumberAliens:   2,
+		InvasionRounds: 10,
+		CityMap:        &Map{cities: generateCityMap(3)},
+		Events: func(e Event) error {
+			if e.Type == EventRound {
+				return stop
+			}
+			return nil
+		},
+	})
+	assert.Equal(t, stop, err)
+}
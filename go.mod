@@ -1,5 +1,13 @@
 module github.com/dhubler/aliens
 
-go 1.16
+go 1.21
 
-require github.com/stretchr/testify v1.8.0 // indirect
+require (
+	github.com/stretchr/testify v1.8.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
@@ -1,78 +1,338 @@
 package aliens
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
-	"log"
-	"math/rand"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"time"
 )
 
-// acoording to spec.  does not include initial round
-const maxRounds = 10000
+// DefaultMaxRounds is acoording to spec.  does not include initial round
+const DefaultMaxRounds = 10000
+
+// UntilOver given as InvasionRounds keeps invasion going until no more cities
+// can be destroyed, still limited by MaxRounds
+const UntilOver = -1
+
+// NoRoundLimit given as MaxRounds removes the limit on rounds. An invasion
+// that runs UntilOver might then never end if aliens keep missing each other
+// so it needs a TimeBudget or a context with a deadline, see ErrNoRoundLimit.
+const NoRoundLimit = -1
+
+// how an invasion ended, see Result.Ended
+const (
+	EndedRounds      = "rounds"      // all rounds asked for were run
+	EndedMaxRounds   = "maxRounds"   // stopped short by MaxRounds
+	EndedNoAliens    = "noAliens"    // every alien was killed or trapped
+	EndedApart       = "apart"       // aliens left can no longer reach the same city
+	EndedCycle       = "cycle"       // aliens left repeat the same moves forever
+	EndedInterrupted = "interrupted" // see Result.Interrupted
+)
 
 // Options to control the invasion
 type Options struct {
 	NumberAliens   int // how many aliens to start invasion
-	InvasionRounds int // rounds to go before giving up
+	InvasionRounds int // rounds to go before giving up or UntilOver
+
+	// most rounds an invasion can run, zero for DefaultMaxRounds or
+	// NoRoundLimit. Result.Ended tells when invasion was stopped short.
+	MaxRounds int
+
+	// optional names of aliens in the order they land.  When given,
+	// NumberAliens is ignored
+	AlienNames []string
+
+	// how aliens pick which road to take, one of the Strategy constants.
+	// Default of empty for StrategyRandom
+	Strategy string
 
 	// controls random seed so invasions are pseudo-random
 	// and therefore deterministic and potentially useful for unit testing
 	// or reproducing a particular invasion
 	Seed int64
 
+	// optional source of random numbers instead of NewPCG(Seed). Seed is then
+	// only reported in result. Invasion draws numbers from it as it goes so
+	// it should not be shared with other invasions.
+	Random Random
+
 	CityMapInput        io.Reader
-	RemaingCitiesOutput io.Writer
+	RemaingCitiesOutput io.Writer // optional
 	StrictMapParse      bool
+
+	// alternative to CityMapInput for invading the same map more than once. Map
+	// is copied so it is not changed by invasion
+	CityMap *Map
+
+	// optional, cities are reported here as they fall in format detailed in
+	// README.md
+	FallenCitiesOutput io.Writer
+
+	// optional, progress of invasion is logged at debug level and more
+	// notable events at info level. nothing is logged when not given
+	Logger *slog.Logger
+
+	// optional wall clock time an invasion can take before it is interrupted
+	TimeBudget time.Duration
+
+	// optional, given the map and where aliens are after landing, then every
+	// RoundMapsEvery rounds and when invasion is over. Invasion stops with
+	// error returned.
+	RoundMaps      func(*RoundMap) error
+	RoundMapsEvery int // zero for every round
+
+	// optional, given every Event as it happens. Invasion stops with error
+	// returned so anything slow should be done elsewhere.
+	Events func(Event) error
+
+	// keep track of every city each alien goes to for Result.Itineraries
+	TrackItineraries bool
+
+	// keep track of what happened to each city for Result.CityHistories
+	TrackCityHistory bool
+}
+
+// ErrTimeBudget is the reason an invasion is interrupted when it runs longer
+// than Options.TimeBudget
+var ErrTimeBudget = errors.New("invasion time budget exceeded")
+
+// ErrNoRoundLimit is returned when an invasion runs UntilOver with
+// NoRoundLimit and has neither a TimeBudget nor a context with a deadline to
+// make sure it ends
+var ErrNoRoundLimit = errors.New("invasion until over with no round limit needs a time budget or a deadline")
+
+// Invade interface to run invasion simulation.
+func Invade(options Options) (*Result, error) {
+	return InvadeContext(context.Background(), options)
+}
+
+// InvadeContext runs invasion until it is over or until context is done or
+// the time budget is used up, checking between each landing and each round.
+// When interrupted, the error is the reason and the result is still returned
+// with the state of the invasion at that point.
+func InvadeContext(ctx context.Context, options Options) (*Result, error) {
+	invasion, err := NewInvasion(options)
+	if err != nil {
+		return nil, err
+	}
+	return invasion.Run(ctx)
 }
 
-// NewInvasion interface to run invasion simulation.
-func Invade(options Options) error {
+// NewInvasion sets up an invasion to Run. Use Invade unless invasion might
+// need to be paused with a Snapshot.
+func NewInvasion(options Options) (*Invasion, error) {
 	invasion := &Invasion{
-		aliens: createAliens(options.NumberAliens),
-		rnd:    rand.New(rand.NewSource(options.Seed)),
-		rounds: options.InvasionRounds,
+		aliens:     createAliens(options.NumberAliens),
+		rounds:     options.InvasionRounds,
+		maxRounds:  options.MaxRounds,
+		strategy:   options.Strategy,
+		lastMapped: -1,
 	}
-	log.Printf("using random seed %d", options.Seed)
-	var err error
-	invasion.cities, err = parse(options.CityMapInput, options.StrictMapParse)
-	if err != nil {
-		return err
+	if err := checkStrategy(options.Strategy); err != nil {
+		return nil, err
+	}
+	invasion.outputs(options)
+	invasion.seed = options.Seed
+	invasion.rnd = options.Random
+	if invasion.rnd == nil {
+		invasion.rnd = NewPCG(options.Seed)
+	}
+	if len(options.AlienNames) > 0 {
+		var err error
+		if invasion.aliens, err = namedAliens(options.AlienNames); err != nil {
+			return nil, err
+		}
 	}
-	invasion.invade()
-	return dump(options.RemaingCitiesOutput, invasion.remaining)
+	if options.TrackItineraries {
+		invasion.trackItineraries()
+	}
+	if options.CityMap != nil {
+		if err := unfollowed(options.CityMap.includes); err != nil {
+			return nil, err
+		}
+		invasion.cities = options.CityMap.clone().cities
+	} else {
+		var err error
+		invasion.cities, err = parse(options.CityMapInput, options.StrictMapParse)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(invasion.cities) == 0 {
+		return nil, errors.New("city map has no cities to invade")
+	}
+	invasion.moveDirections = len(directions)
+	if compassOnly(invasion.cities) {
+		// so a seed gives the same invasion of a compass map it always has
+		invasion.moveDirections = len(compassLabels)
+	}
+	if options.TrackCityHistory {
+		invasion.trackCityHistory()
+	}
+	return invasion, nil
 }
 
+// outputs are the only options that can change when invasion is resumed
+func (sim *Invasion) outputs(options Options) {
+	sim.log = options.Logger
+	if sim.log == nil {
+		sim.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	sim.fallenOutput = options.FallenCitiesOutput
+	sim.remainingOutput = options.RemaingCitiesOutput
+	sim.timeBudget = options.TimeBudget
+	sim.roundMaps = options.RoundMaps
+	sim.roundMapsEvery = options.RoundMapsEvery
+	sim.events = options.Events
+}
+
+// Run invasion from wherever it was left off until it is over or until
+// context is done or the time budget is used up. See InvadeContext.
+func (sim *Invasion) Run(ctx context.Context) (*Result, error) {
+	if _, deadline := ctx.Deadline(); sim.rounds == UntilOver && sim.maxRounds == NoRoundLimit && sim.timeBudget <= 0 && !deadline {
+		return nil, ErrNoRoundLimit
+	}
+	if sim.timeBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, sim.timeBudget, ErrTimeBudget)
+		defer cancel()
+	}
+	if sim.landed == 0 {
+		sim.log.Info("invasion starting", "seed", sim.seed)
+	} else {
+		sim.log.Info("invasion resuming", "seed", sim.seed, "rounds", sim.roundsCompleted)
+	}
+	sim.interrupted = nil
+	if err := sim.invade(ctx); err != nil {
+		return nil, err
+	}
+	if sim.remainingOutput != nil {
+		if err := dump(sim.remainingOutput, sim.remaining); err != nil {
+			return nil, err
+		}
+	}
+	result := &Result{
+		Seed:          sim.seed,
+		Rounds:        sim.roundsCompleted,
+		Ended:         sim.ended,
+		Remaining:     cityNames(sim.remaining),
+		Fallen:        sim.fallen,
+		AliensLeft:    sim.aliensLeft,
+		AliensTrapped: sim.aliensTrapped,
+		Fragmentation: fragmentation(sim.remaining, sim.severed),
+		Itineraries:   sim.itineraryList(sim.interrupted == nil),
+		CityHistories: sim.cityHistoryList(),
+	}
+	if sim.interrupted != nil {
+		result.Interrupted = sim.interrupted.Error()
+		return result, sim.interrupted
+	}
+	return result, nil
+}
+
+// Invasion is an invasion in progress. Everything about it can be kept in a
+// Snapshot to resume it later.
 type Invasion struct {
-	rnd       *rand.Rand
-	cities    map[string]*city
-	remaining map[string]*city
-	aliens    []alien
-	rounds    int
+	log             *slog.Logger
+	fallenOutput    io.Writer
+	remainingOutput io.Writer
+	timeBudget      time.Duration
+	roundMaps       func(*RoundMap) error
+	roundMapsEvery  int
+	events          func(Event) error
+	lastMapped      int // round last given to roundMaps
+	seed            int64
+	rnd             Random
+	cities          map[string]*city
+	moveDirections  int // directions aliens pick from when moving
+	strategy        string
+	remaining       map[string]*city
+	aliens          []alien
+	rounds          int
+	maxRounds       int
+	severed         []Road
+	fallen          []FallenCity
+	itineraries     map[alien]*Itinerary    // nil unless tracked
+	cityHistories   map[string]*CityHistory // nil unless tracked
+
+	// state of invasion between landings and rounds
+	landed    int // aliens that have landed
+	destroyed map[string]*city
+	invaded   map[*city]alien
+	trapped   map[*city]alien
+
+	// when every alien has only one road to take, invasion is no longer
+	// random and seeing the same aliens in the same cities again means it is
+	// going in circles. cleared whenever that is not the case
+	seen            map[string]bool
+	roundsCompleted int
+
+	aliensLeft    int
+	aliensTrapped int
+	ended         string
+	interrupted   error
 }
 
 // invade simulates aliens navigating a map of cities according to a set of
 // rules outlined in README.md.
-// returns list of cities that are remaining after invasion
-func (sim *Invasion) invade() {
-	destroyedCities := make(map[string]*city)
-	invadedCities := make(map[*city]alien)
-	trappedAlienCities := make(map[*city]alien)
-	startCityNames := cityNames(sim.cities)
+// remaining cities are left in sim.remaining and if context is done before
+// invasion is over, the reason is left in sim.interrupted and invade can be
+// called again to continue. only errors returned are from outputs: fallen
+// cities, round maps and events
+func (sim *Invasion) invade(ctx context.Context) error {
+	if sim.destroyed == nil {
+		sim.destroyed = make(map[string]*city)
+		sim.invaded = make(map[*city]alien)
+		sim.trapped = make(map[*city]alien)
+	}
+	if sim.ended == "" || sim.ended == EndedInterrupted {
+		if err := sim.land(ctx); err != nil {
+			return err
+		}
+		if err := sim.move(ctx); err != nil {
+			return err
+		}
+	}
 
-	if sim.rounds > maxRounds {
-		log.Printf("warning, limited to %d exceeds maximum rounds or %d", sim.rounds, maxRounds)
-		sim.rounds = maxRounds
+	// remaining = original list - destroyed
+	sim.remaining = make(map[string]*city)
+	for name, city := range sim.cities {
+		if _, destroyed := sim.destroyed[name]; !destroyed {
+			sim.remaining[name] = city
+		}
 	}
 
-	// start aliens in random cities, cities can be destroyed in this phase
-	log.Print("invasion starting round")
-	for _, alien := range sim.aliens {
+	sim.aliensLeft = len(sim.invaded)
+	sim.aliensTrapped = len(sim.trapped)
+	sim.log.Info("invasion over", "ended", sim.ended, "citiesLeft", len(sim.remaining), "aliensLeft", sim.aliensLeft, "aliensTrapped", sim.aliensTrapped)
+	return nil
+}
+
+// land aliens in random cities, cities can be destroyed in this phase
+func (sim *Invasion) land(ctx context.Context) error {
+	startCityNames := cityNames(sim.cities)
+	if sim.landed == 0 {
+		sim.log.Debug("invasion starting round")
+	}
+	landing := sim.landed < len(sim.aliens)
+	for sim.landed < len(sim.aliens) {
+		if sim.checkInterrupted(ctx) {
+			return nil
+		}
+		alien := sim.aliens[sim.landed]
 		cityIndex := sim.rnd.Intn(len(sim.cities))
 	reattemptLanding:
 		city := sim.cities[startCityNames[cityIndex]]
-		if _, alreadyDestroyed := destroyedCities[city.Name]; alreadyDestroyed {
+		if _, alreadyDestroyed := sim.destroyed[city.Name]; alreadyDestroyed {
 			// avoid landing in cities that were already destroyed in this initial round
-			if len(destroyedCities) == len(sim.cities) {
+			if len(sim.destroyed) == len(sim.cities) {
 				// no more cities to attack
+				sim.landed = len(sim.aliens)
 				break
 			}
 			// go to next city, do not pick another random city because if there is
@@ -80,66 +340,234 @@ func (sim *Invasion) invade() {
 			cityIndex = (cityIndex + 1) % len(sim.cities)
 			goto reattemptLanding
 		}
-		sim.invadeCity(alien, city, invadedCities, destroyedCities)
+		if err := sim.emit(Event{Type: EventLanded, Alien: string(alien), City: city.Name}); err != nil {
+			return err
+		}
+		if err := sim.invadeCity(alien, city); err != nil {
+			return err
+		}
+		sim.landed++
+	}
+	if landing {
+		return sim.emit(Event{Type: EventRound})
 	}
+	return nil
+}
 
-	// move aliens around until rounds are done
-	for i := 0; i < sim.rounds; i++ {
-		log.Printf("invasion %d round", i+1)
-		currentCities := invadedCities
+// move aliens around until rounds are done
+func (sim *Invasion) move(ctx context.Context) error {
+	if sim.interrupted != nil {
+		return nil
+	}
+	if err := sim.mapRound(false); err != nil {
+		return err
+	}
+	untilOver := sim.rounds == UntilOver
+	rounds, ended := sim.rounds, EndedRounds
+	maxRounds := sim.maxRounds
+	if maxRounds == 0 {
+		maxRounds = DefaultMaxRounds
+	}
+	if maxRounds != NoRoundLimit && (untilOver || rounds > maxRounds) {
+		rounds, ended = maxRounds, EndedMaxRounds
+	} else if untilOver {
+		rounds = math.MaxInt
+	}
+	for i := sim.roundsCompleted; i < rounds; i++ {
+		if sim.checkInterrupted(ctx) {
+			return nil
+		}
+		sim.log.Debug("invasion round", "round", i+1)
+		sim.roundsCompleted = i + 1
+		currentCities := sim.invaded
 
 		// we iterate the sorted city names to allow for pseudom random test
 		// cases.  Otherwise iterating invadedCities would be bit faster and
 		// simpler
 		currentCitiesNames := invadedCityNames(currentCities)
 
-		invadedCities = make(map[*city]alien)
+		sim.invaded = make(map[*city]alien)
+		fallen := len(sim.fallen)
+		forced := true
+		moved := make(map[*city]bool)
 		for _, origCityName := range currentCitiesNames {
 			origCity := sim.cities[origCityName]
 			alien := currentCities[origCity]
-			city := sim.nextRandomCity(origCity)
+			if origCity.roadsOut() > 1 {
+				forced = false
+			}
+			// other aliens are either yet to move or have moved already
+			city := sim.nextCity(origCity, func(c *city) bool {
+				_, waiting := currentCities[c]
+				_, arrived := sim.invaded[c]
+				return (waiting && !moved[c] && c != origCity) || arrived
+			})
+			moved[origCity] = true
 			if city == nil {
-				trappedAlienCities[origCity] = alien
-			} else {
-				sim.invadeCity(alien, city, invadedCities, destroyedCities)
+				sim.trapped[origCity] = alien
+				sim.fate(alien, FateTrapped)
+				if err := sim.emit(Event{Type: EventTrapped, Alien: string(alien), City: origCity.Name}); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := sim.emit(Event{Type: EventMoved, Alien: string(alien), From: origCity.Name, City: city.Name}); err != nil {
+				return err
+			}
+			if err := sim.invadeCity(alien, city); err != nil {
+				return err
 			}
 		}
-		if len(invadedCities) == 0 {
+		if err := sim.emit(Event{Type: EventRound}); err != nil {
+			return err
+		}
+		over := sim.over(untilOver, forced, len(sim.fallen) > fallen)
+		if err := sim.mapRound(false); err != nil {
+			return err
+		}
+		if over != "" {
+			ended = over
 			break
 		}
 	}
+	sim.ended = ended
+	if err := sim.emit(Event{Type: EventOver, Ended: ended}); err != nil {
+		return err
+	}
+	return sim.mapRound(true)
+}
 
-	// remaining = original list - destroyed
-	sim.remaining = make(map[string]*city)
-	for name, city := range sim.cities {
-		if _, destroyed := destroyedCities[name]; !destroyed {
-			sim.remaining[name] = city
+// over is how invasion ended with the round just completed or empty string if
+// invasion goes on
+func (sim *Invasion) over(untilOver bool, forced bool, fell bool) string {
+	if len(sim.invaded) == 0 {
+		return EndedNoAliens
+	}
+	if !untilOver {
+		return ""
+	}
+	if !canMeet(sim.invaded, sim.destroyed) {
+		return EndedApart
+	}
+	if !forced || fell {
+		sim.seen = nil
+		return ""
+	}
+	if sim.seen == nil {
+		sim.seen = make(map[string]bool)
+	}
+	positions := alienPositions(sim.invaded)
+	if sim.seen[positions] {
+		return EndedCycle
+	}
+	sim.seen[positions] = true
+	return ""
+}
+
+// mapRound gives map to Options.RoundMaps every so many rounds and when
+// invasion is over
+func (sim *Invasion) mapRound(final bool) error {
+	if sim.roundMaps == nil || sim.lastMapped == sim.roundsCompleted {
+		return nil
+	}
+	every := sim.roundMapsEvery
+	if every <= 0 {
+		every = 1
+	}
+	if !final && sim.roundsCompleted%every != 0 {
+		return nil
+	}
+	sim.lastMapped = sim.roundsCompleted
+	return sim.roundMaps(sim.roundMap())
+}
+
+// checkInterrupted keeps reason when context is done
+func (sim *Invasion) checkInterrupted(ctx context.Context) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	sim.ended = EndedInterrupted
+	sim.interrupted = fmt.Errorf("interrupted after %d round(s). %w", sim.roundsCompleted, context.Cause(ctx))
+	sim.log.Warn("invasion interrupted", "rounds", sim.roundsCompleted, "reason", context.Cause(ctx))
+	return true
+}
+
+// canMeet is false when no two roaming aliens can reach the same city by any
+// roads so no more cities can be destroyed
+func canMeet(invadedCities map[*city]alien, destroyedCities map[string]*city) bool {
+	reachedFrom := make(map[*city]*city)
+	for start := range invadedCities {
+		pending := []*city{start}
+		for len(pending) > 0 {
+			c := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			if from, reached := reachedFrom[c]; reached {
+				if from != start {
+					return true
+				}
+				continue
+			}
+			reachedFrom[c] = start
+			for direction := range directions {
+				neighbor := c.neighoringCity(direction)
+				if neighbor == nil {
+					continue
+				}
+				if _, destroyed := destroyedCities[neighbor.Name]; !destroyed {
+					pending = append(pending, neighbor)
+				}
+			}
 		}
 	}
+	return false
+}
 
-	log.Printf("%d cities left, %d alien(s) left, %d alien(s) trapped", len(sim.remaining), len(invadedCities), len(trappedAlienCities))
+// alienPositions identifies which alien is in which city
+func alienPositions(invadedCities map[*city]alien) string {
+	positions := make([]string, 0, len(invadedCities))
+	for c, a := range invadedCities {
+		positions = append(positions, fmt.Sprintf("%s=%s", c.Name, a))
+	}
+	sort.Strings(positions)
+	return strings.Join(positions, "\n")
 }
 
 // invadeCity checks if another alien is in city to trigger a destroy or if this
 // is just first visit
-func (sim *Invasion) invadeCity(incomingAlien alien, targetCity *city, invadedCities map[*city]alien, destroyedCities map[string]*city) {
-	log.Printf("alien %s invading %s", incomingAlien, targetCity.Name)
-	if invadedAlien, isInvaded := invadedCities[targetCity]; isInvaded {
-		destroyedCities[targetCity.Name] = targetCity
-		delete(invadedCities, targetCity) // leaves aliens inside
-		log.Printf("%s has been destroyed by alien %s and alien %s!\n", targetCity.Name, incomingAlien, invadedAlien)
-		targetCity.destroy(incomingAlien, invadedAlien)
+func (sim *Invasion) invadeCity(incomingAlien alien, targetCity *city) error {
+	sim.log.Debug("alien invading", "alien", incomingAlien, "city", targetCity.Name)
+	sim.stop(incomingAlien, targetCity)
+	sim.visit(incomingAlien, targetCity)
+	if invadedAlien, isInvaded := sim.invaded[targetCity]; isInvaded {
+		sim.destroyed[targetCity.Name] = targetCity
+		delete(sim.invaded, targetCity) // leaves aliens inside
+		fallen := FallenCity{City: targetCity.Name, Aliens: [2]string{string(incomingAlien), string(invadedAlien)}}
+		sim.fallen = append(sim.fallen, fallen)
+		sim.log.Info("city destroyed", "city", fallen.City, "alien1", fallen.Aliens[0], "alien2", fallen.Aliens[1])
+		severed := targetCity.destroy(sim.cities, incomingAlien, invadedAlien)
+		sim.severed = append(sim.severed, severed...)
+		sim.destruction(fallen)
+		sim.sever(targetCity, severed)
+		sim.fate(incomingAlien, FateKilled)
+		sim.fate(invadedAlien, FateKilled)
+		if sim.fallenOutput != nil {
+			if _, err := fmt.Fprintln(sim.fallenOutput, fallen); err != nil {
+				return err
+			}
+		}
+		return sim.emit(Event{Type: EventDestroyed, Alien: fallen.Aliens[0], OtherAlien: fallen.Aliens[1], City: fallen.City})
 	} else {
-		invadedCities[targetCity] = incomingAlien
+		sim.invaded[targetCity] = incomingAlien
 	}
+	return nil
 }
 
 // nextRandomCity picks a random neighboring city or return nil if
 // there are no cities left
 func (sim *Invasion) nextRandomCity(c *city) *city {
-	startCityIndex := sim.rnd.Intn(len(directions))
-	for i := 0; i < len(directions); i++ {
-		candidateIndex := (startCityIndex + i) % len(directions)
+	startCityIndex := sim.rnd.Intn(sim.moveDirections)
+	for i := 0; i < sim.moveDirections; i++ {
+		candidateIndex := (startCityIndex + i) % sim.moveDirections
 		candidate := c.neighoringCity(candidateIndex)
 		if candidate != nil {
 			return candidate
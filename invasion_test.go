@@ -2,13 +2,19 @@ package aliens
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
-	"log"
-	"math/rand"
+	"log/slog"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 var updateFlag = flag.Bool("update", false, "update expected golden file(s)")
@@ -20,71 +26,128 @@ func TestInvasions(t *testing.T) {
 		expected string
 	}{
 		{
-			seed:     1657964729860941318,
+			seed:     1,
 			cities:   "testdata/small-map.txt",
 			expected: "testdata/aliens-lose.golden",
 		},
 		{
-			seed:     1657982898578641344,
+			seed:     20,
 			cities:   "testdata/small-map.txt",
 			expected: "testdata/aliens-oscilating.golden",
 		},
 		{
-			seed:     10,
+			seed:     4,
 			cities:   "testdata/small-map.txt",
 			expected: "testdata/aliens-trapped.golden",
 		},
 		{
-			seed: 10,
+			seed: 1,
 			// if you go north enough, even south is eventually north on a sphere
 			cities:   "testdata/circular-map.txt",
 			expected: "testdata/nothing-left.golden",
 		},
 	}
 	var buf bytes.Buffer
-	resetLog := divertGlobalLogger(&buf)
-	defer resetLog()
 	for _, test := range tests {
 		buf.Reset()
 		in, err := os.Open(test.cities)
 		if err != nil {
 			t.Fatal(err)
 		}
-		err = Invade(Options{
+		_, err = Invade(Options{
 			Seed:                test.seed,
 			RemaingCitiesOutput: &buf,
 			NumberAliens:        10,
 			InvasionRounds:      10,
 			CityMapInput:        in,
+			FallenCitiesOutput:  &buf,
+			Logger:              testLogger(&buf),
 		})
 		in.Close()
 		Golden(t, *updateFlag, test.expected, &buf)
 	}
 }
 
-// for unit test that want to capture and verify log output.  be sure to call returned
-// function in defer
-func divertGlobalLogger(capture io.Writer) func() {
-	orig := log.Default().Flags()
-	log.SetFlags(0)
-	log.SetOutput(capture)
-	return func() {
-		log.SetFlags(orig)
-		log.SetOutput(os.Stderr)
+// for unit test that want to capture and verify log output. time is left out
+// so log output can be compared to golden files
+func testLogger(capture io.Writer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(capture, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestParallelInvasions(t *testing.T) {
+	cityMap := &Map{cities: generateCityMap(5)}
+	names := make([]string, 50)
+	for i := range names {
+		names[i] = fmt.Sprintf("alien-%d", i)
+	}
+	// everything an invasion outputs so parallel and sequential invasions can
+	// be compared
+	invade := func(seed int64) string {
+		var buf bytes.Buffer
+		result, err := Invade(Options{
+			Seed:                seed,
+			AlienNames:          names,
+			InvasionRounds:      100,
+			CityMap:             cityMap,
+			RemaingCitiesOutput: &buf,
+			FallenCitiesOutput:  &buf,
+			Logger:              testLogger(&buf),
+		})
+		if err != nil {
+			t.Error(err)
+			return ""
+		}
+		if err = json.NewEncoder(&buf).Encode(result); err != nil {
+			t.Error(err)
+		}
+		return buf.String()
+	}
+	const runs = 40
+	expected := make([]string, runs)
+	for i := range expected {
+		expected[i] = invade(int64(i))
+	}
+	actual := make([]string, runs)
+	var wg sync.WaitGroup
+	for i := range actual {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actual[i] = invade(int64(i))
+		}(i)
+	}
+	wg.Wait()
+	for i := range expected {
+		assert.Equal(t, expected[i], actual[i], "seed %d", i)
 	}
+	// shared map is left untouched
+	var buf bytes.Buffer
+	assert.NoError(t, cityMap.Write(&buf))
+	Golden(t, *updateFlag, "testdata/large-dump-map.golden", &buf)
 }
 
 func TestMediumInvasion(t *testing.T) {
 	var buf bytes.Buffer
-	resetLog := divertGlobalLogger(&buf)
-	defer resetLog()
 	invasion := &Invasion{
-		rnd:    rand.New(rand.NewSource(0)),
-		cities: generateCityMap(10),
-		aliens: createAliens(100),
-		rounds: 200,
+		log:            testLogger(&buf),
+		fallenOutput:   &buf,
+		cities:         generateCityMap(10),
+		moveDirections: len(compassLabels),
+		aliens:         createAliens(100),
+		rounds:         200,
+		rnd:            NewPCG(0),
+	}
+	if err := invasion.invade(context.Background()); err != nil {
+		t.Fatal(err)
 	}
-	invasion.invade()
 	err := dump(&buf, invasion.remaining)
 	if err != nil {
 		t.Fatal(err)
@@ -122,7 +185,7 @@ func generateCityMapNest(levels int, parent *city, pool map[string]*city) {
 	// pass 1 : add all the children first before recursing otherwise neighbors will
 	// be different
 	labels := []string{"^", "v", ">", "<"}
-	for direction := range directions {
+	for direction := range labels {
 		if parent.neighoringCity(direction) == nil {
 			neighbor := &city{Name: fmt.Sprintf("%s%s", parent.Name, labels[direction])}
 			pool[neighbor.Name] = neighbor
@@ -136,3 +199,153 @@ func generateCityMapNest(levels int, parent *city, pool map[string]*city) {
 		generateCityMapNest(levels-1, child, pool)
 	}
 }
+
+// cancelWriter cancels context on first write
+type cancelWriter struct {
+	cancel context.CancelFunc
+}
+
+func (w cancelWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return len(p), nil
+}
+
+func TestInvadeContext(t *testing.T) {
+	cityMap := &Map{cities: generateCityMap(5)}
+
+	t.Run("canceled before start", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		result, err := InvadeContext(ctx, Options{NumberAliens: 10, InvasionRounds: 10, CityMap: cityMap})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0, result.Rounds)
+		assert.Equal(t, cityMap.Len(), len(result.Remaining))
+		assert.Equal(t, "interrupted after 0 round(s). context canceled", result.Interrupted)
+	})
+
+	t.Run("canceled when first city falls", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		result, err := InvadeContext(ctx, Options{
+			NumberAliens:       100,
+			InvasionRounds:     10,
+			CityMap:            cityMap,
+			FallenCitiesOutput: cancelWriter{cancel},
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, len(result.Fallen))
+		assert.Equal(t, cityMap.Len()-1, len(result.Remaining))
+	})
+
+	t.Run("time budget", func(t *testing.T) {
+		result, err := InvadeContext(context.Background(), Options{
+			NumberAliens:   100,
+			InvasionRounds: 10,
+			CityMap:        cityMap,
+			TimeBudget:     time.Nanosecond,
+		})
+		assert.ErrorIs(t, err, ErrTimeBudget)
+		assert.NotNil(t, result)
+		assert.Contains(t, result.Interrupted, ErrTimeBudget.Error())
+	})
+
+	t.Run("not interrupted", func(t *testing.T) {
+		result, err := InvadeContext(context.Background(), Options{
+			NumberAliens:   100,
+			InvasionRounds: 10,
+			CityMap:        cityMap,
+			TimeBudget:     time.Hour,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "", result.Interrupted)
+	})
+}
+
+func TestInvadeNoCities(t *testing.T) {
+	_, err := Invade(Options{NumberAliens: 2, CityMapInput: strings.NewReader("# nothing here\n")})
+	assert.EqualError(t, err, "city map has no cities to invade")
+}
+
+func TestNoRoundLimit(t *testing.T) {
+	options := Options{
+		NumberAliens:   2,
+		InvasionRounds: UntilOver,
+		MaxRounds:      NoRoundLimit,
+		CityMapInput:   strings.NewReader("A north=B\n"),
+	}
+	_, err := Invade(options)
+	assert.ErrorIs(t, err, ErrNoRoundLimit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	options.CityMapInput = strings.NewReader("A north=B\n")
+	_, err = InvadeContext(ctx, options)
+	assert.NoError(t, err)
+}
+
+func TestInvadeOneWay(t *testing.T) {
+	// B can be reached by one-way roads from cities that are not its neighbors
+	cities := "A east=B\nB east=C\nC east=D\nD north=B\n"
+	for seed := int64(0); seed < 20; seed++ {
+		var remaining bytes.Buffer
+		result, err := Invade(Options{
+			Seed:                seed,
+			NumberAliens:        4,
+			InvasionRounds:      100,
+			CityMapInput:        strings.NewReader(cities),
+			StrictMapParse:      true,
+			RemaingCitiesOutput: &remaining,
+		})
+		assert.NoError(t, err)
+		destroyed := make(map[string]bool)
+		for _, fallen := range result.Fallen {
+			assert.False(t, destroyed[fallen.City], "seed %d destroyed %s twice", seed, fallen.City)
+			destroyed[fallen.City] = true
+		}
+		left, err := ParseMap(&remaining, true)
+		assert.NoError(t, err)
+		for _, name := range left.CityNames() {
+			for direction := range directions {
+				to := left.Neighbor(name, direction)
+				assert.False(t, destroyed[to], "seed %d kept road from %s to %s", seed, name, to)
+			}
+		}
+	}
+}
+
+func TestInvasionEnded(t *testing.T) {
+	ring := "Boston north=Bangor\nBangor north=Trenton\nTrenton north=Boston\n"
+	apart := "A north=B\nC north=D\n"
+	tests := []struct {
+		cities    string
+		seed      int64
+		rounds    int
+		maxRounds int
+		budget    time.Duration
+		ended     string
+		expected  int
+	}{
+		{cities: ring, seed: 0, rounds: UntilOver, ended: EndedNoAliens, expected: 1},
+		{cities: ring, seed: 2, rounds: 5, ended: EndedRounds, expected: 5},
+		{cities: ring, seed: 2, rounds: 100, maxRounds: 5, ended: EndedMaxRounds, expected: 5},
+		{cities: ring, seed: 2, rounds: UntilOver, ended: EndedCycle, expected: 4},
+		{cities: ring, seed: 2, rounds: UntilOver, maxRounds: NoRoundLimit, budget: time.Hour, ended: EndedCycle, expected: 4},
+		{cities: ring, seed: 2, rounds: UntilOver, maxRounds: 2, ended: EndedMaxRounds, expected: 2},
+		{cities: apart, seed: 3, rounds: UntilOver, ended: EndedApart, expected: 1},
+		{cities: apart, seed: 3, rounds: 10, ended: EndedNoAliens, expected: 2},
+	}
+	for _, test := range tests {
+		result, err := Invade(Options{
+			Seed:           test.seed,
+			AlienNames:     []string{"x", "y"},
+			InvasionRounds: test.rounds,
+			MaxRounds:      test.maxRounds,
+			TimeBudget:     test.budget,
+			CityMapInput:   strings.NewReader(test.cities),
+			StrictMapParse: true,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, test.ended, result.Ended, "%+v", test)
+		assert.Equal(t, test.expected, result.Rounds, "%+v", test)
+	}
+}
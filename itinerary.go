@@ -0,0 +1,116 @@
+package aliens
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// what became of an alien, see Itinerary.Fate
+const (
+	FateKilled    = "killed"    // in a city it destroyed
+	FateTrapped   = "trapped"   // in a city without roads out
+	FateRoaming   = "roaming"   // still going when invasion was over
+	FateNotLanded = "notLanded" // every city was destroyed before it could land
+)
+
+// Itinerary is every city an alien went to from landing until it was killed,
+// trapped or the invasion was over
+type Itinerary struct {
+	Alien string `json:"alien"`
+	Stops []Stop `json:"stops"`
+	Fate  string `json:"fate"`
+
+	// round alien was killed or found trapped, zero when still roaming
+	FateRound int `json:"fateRound"`
+}
+
+// Stop is a city alien arrived at in a round, round zero is landing
+type Stop struct {
+	Round int    `json:"round"`
+	City  string `json:"city"`
+}
+
+// Itineraries of all aliens in the order they land
+type Itineraries []Itinerary
+
+// trackItineraries starts an itinerary for every alien
+func (sim *Invasion) trackItineraries() {
+	sim.itineraries = make(map[alien]*Itinerary, len(sim.aliens))
+	for _, a := range sim.aliens {
+		sim.itineraries[a] = &Itinerary{Alien: string(a)}
+	}
+}
+
+// stop and fate are harmless to call when itineraries are not tracked
+func (sim *Invasion) stop(a alien, c *city) {
+	if i, tracked := sim.itineraries[a]; tracked {
+		i.Stops = append(i.Stops, Stop{Round: sim.roundsCompleted, City: c.Name})
+	}
+}
+
+func (sim *Invasion) fate(a alien, fate string) {
+	if i, tracked := sim.itineraries[a]; tracked {
+		i.Fate = fate
+		i.FateRound = sim.roundsCompleted
+	}
+}
+
+// itineraryList is nil when itineraries are not tracked. Aliens without a
+// fate yet are given one when invasion is over.
+func (sim *Invasion) itineraryList(over bool) Itineraries {
+	if sim.itineraries == nil {
+		return nil
+	}
+	list := make(Itineraries, 0, len(sim.aliens))
+	for _, a := range sim.aliens {
+		i := *sim.itineraries[a]
+		i.Stops = append([]Stop(nil), i.Stops...)
+		if over && i.Fate == "" {
+			if len(i.Stops) == 0 {
+				i.Fate = FateNotLanded
+			} else {
+				i.Fate = FateRoaming
+			}
+		}
+		list = append(list, i)
+	}
+	return list
+}
+
+// WriteCSV writes a row for every stop with columns alien, round and city.
+// Fate is only on last row of each alien and a row with no round or city is
+// written for aliens that never landed.
+func (itineraries Itineraries) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"alien", "round", "city", "fate"}); err != nil {
+		return err
+	}
+	for _, i := range itineraries {
+		if len(i.Stops) == 0 {
+			if err := out.Write([]string{i.Alien, "", "", i.Fate}); err != nil {
+				return err
+			}
+			continue
+		}
+		for n, stop := range i.Stops {
+			fate := ""
+			if n == len(i.Stops)-1 {
+				fate = i.Fate
+			}
+			if err := out.Write([]string{i.Alien, strconv.Itoa(stop.Round), stop.City, fate}); err != nil {
+				return err
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// WriteJSON writes itineraries as JSON document
+func (itineraries Itineraries) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(itineraries)
+}
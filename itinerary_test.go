@@ -0,0 +1,44 @@
+package aliens
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItineraries(t *testing.T) {
+	in, err := os.Open("testdata/small-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	result, err := Invade(Options{
+		Seed:             20,
+		NumberAliens:     10,
+		InvasionRounds:   3,
+		CityMapInput:     in,
+		TrackItineraries: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 10, len(result.Itineraries))
+	killed := 0
+	for _, i := range result.Itineraries {
+		if i.Fate == FateKilled {
+			killed++
+		}
+	}
+	assert.Equal(t, result.AliensKilled(), killed)
+
+	var buf bytes.Buffer
+	assert.NoError(t, result.Itineraries.WriteCSV(&buf))
+	Golden(t, *updateFlag, "testdata/itineraries.csv", &buf)
+	assert.NoError(t, result.Itineraries.WriteJSON(&buf))
+	Golden(t, *updateFlag, "testdata/itineraries.json", &buf)
+
+	// not tracked unless asked for
+	result, err = Invade(Options{NumberAliens: 10, CityMap: &Map{cities: generateCityMap(2)}})
+	assert.NoError(t, err)
+	assert.Nil(t, result.Itineraries)
+}
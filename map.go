@@ -0,0 +1,202 @@
+package aliens
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Map is a set of cities connected by roads in compass directions, up or down. It is the
+// exported form of what parse reads and dump writes so other packages can build
+// maps without going thru the text format.
+type Map struct {
+	cities   map[string]*city
+	header   []string // comment lines at top of map file
+	includes []string // files map includes that were not read
+	comments []int    // line numbers of comments below header
+	strict   bool     // roads only go where given, no roads back are implied
+}
+
+// NewMap creates an empty map
+func NewMap() *Map {
+	return &Map{cities: make(map[string]*city)}
+}
+
+// ParseMap reads a map in the format detailed in README.md. Include lines are
+// kept but not followed since there is no file to find other maps relative
+// to, see ParseMapFile.
+func ParseMap(r io.Reader, strict bool) (*Map, error) {
+	mf, err := readMapFile(r, "", strict)
+	if err != nil {
+		return nil, err
+	}
+	cities, err := buildCities(mf.refs)
+	if err != nil {
+		return nil, err
+	}
+	return &Map{cities: cities, header: mf.header, includes: mf.includes, comments: mf.comments}, nil
+}
+
+// Comments are line numbers of comments below the header, from the first
+// city on. Only the header is kept when map is written so these are lost.
+func (m *Map) Comments() []int {
+	return m.comments
+}
+
+// Includes are files named by include lines that were not followed because
+// map was not read by ParseMapFile. Invasions of such maps are not allowed.
+func (m *Map) Includes() []string {
+	return m.includes
+}
+
+// AddCity adds a city with no roads if it doesn't already exist
+func (m *Map) AddCity(name string) {
+	m.city(name)
+}
+
+// AddRoad connects two cities, adding either city if it doesn't exist yet. Like
+// the default parse, the road back in the opposite direction is implied.
+func (m *Map) AddRoad(from string, direction int, to string) error {
+	if direction < 0 || direction >= len(directions) {
+		return fmt.Errorf("invalid direction %d", direction)
+	}
+	return m.city(from).addNeighborBidiectional(direction, m.city(to))
+}
+
+func (m *Map) city(name string) *city {
+	c, exists := m.cities[name]
+	if !exists {
+		c = &city{Name: name}
+		m.cities[name] = c
+	}
+	return c
+}
+
+// Len is the number of cities in map
+func (m *Map) Len() int {
+	return len(m.cities)
+}
+
+// CityNames are in city name sorted order
+func (m *Map) CityNames() []string {
+	return cityNames(m.cities)
+}
+
+// Neighbor is name of neighboring city in given direction or empty string if
+// there is no road in that direction
+func (m *Map) Neighbor(name string, direction int) string {
+	c, exists := m.cities[name]
+	if !exists {
+		return ""
+	}
+	if neighbor := c.neighoringCity(direction); neighbor != nil {
+		return neighbor.Name
+	}
+	return ""
+}
+
+// Write map in same format as ParseMap reads, including comments that were at
+// top of map file
+func (m *Map) Write(w io.Writer) error {
+	return dumpWithHeader(w, m.writtenHeader(), m.cities)
+}
+
+// WriteOmitImplied writes map in same format as ParseMap reads but without
+// roads back in the opposite direction that a parse that is not strict adds on
+// its own. It is an error if a road has no road back.
+func (m *Map) WriteOmitImplied(w io.Writer) error {
+	return dumpOmitImplied(w, m.writtenHeader(), m.cities)
+}
+
+// writtenHeader is header with a #!strict directive when map was imported strictly
+// but header does not say so, otherwise map would not read back the same
+func (m *Map) writtenHeader() []string {
+	if strict, _ := headerDirectives(m.header); m.strict && !strict {
+		return append([]string{"#!strict"}, m.header...)
+	}
+	return m.header
+}
+
+// Road is a one way road from one city to another
+type Road struct {
+	From      string `json:"from"`
+	Direction string `json:"direction"`
+	To        string `json:"to"`
+}
+
+// clone copies cities and roads so changes to one map do not affect the other
+func (m *Map) clone() *Map {
+	cloned := NewMap()
+	for name, c := range m.cities {
+		dup := cloned.city(name)
+		for direction := range directions {
+			if neighbor := c.neighoringCity(direction); neighbor != nil {
+				if err := dup.addNeighbor(direction, cloned.city(neighbor.Name)); err != nil {
+					panic(err)
+				}
+			}
+		}
+	}
+	return cloned
+}
+
+// ReadMapJSON reads a map where each city is a key to an object of directions
+// to neighboring cities.
+// Example:
+//   {"Boston": {"south": "NewYork", "west": "Albany"}, "Albany": {}}
+func ReadMapJSON(r io.Reader, strict bool) (*Map, error) {
+	var doc map[string]map[string]string
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	m := NewMap()
+	m.strict = strict
+	names := make([]string, 0, len(doc))
+	for name := range doc {
+		names = append(names, name)
+	}
+	// sorted so errors are consistent
+	sort.Strings(names)
+	for _, name := range names {
+		c := m.city(name)
+		labels := make([]string, 0, len(doc[name]))
+		for label := range doc[name] {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		for _, label := range labels {
+			neighborName := doc[name][label]
+			// every direction is allowed, there is no header to declare them in
+			direction := directionFromLabel(label)
+			if direction < 0 {
+				return nil, fmt.Errorf("parse error, '%s' is not a recognized direction", label)
+			}
+			if neighborName == "" {
+				return nil, fmt.Errorf("no city name given for %s %s", name, label)
+			}
+			neighbor := m.city(neighborName)
+			var err error
+			if strict {
+				err = c.addNeighbor(direction, neighbor)
+			} else {
+				err = c.addNeighborBidiectional(direction, neighbor)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+// WriteJSON writes map in format ReadMapJSON reads
+func (m *Map) WriteJSON(w io.Writer) error {
+	doc := make(map[string]map[string]string, len(m.cities))
+	for name, c := range m.cities {
+		doc[name] = c.roadLabels()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
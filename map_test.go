@@ -0,0 +1,99 @@
+package aliens
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	m := NewMap()
+	assert.NoError(t, m.AddRoad("Boston", North, "Bangor"))
+	assert.NoError(t, m.AddRoad("Boston", West, "Albany"))
+	m.AddCity("Columbus")
+	assert.Error(t, m.AddRoad("Boston", North, "Albany"))
+	assert.Error(t, m.AddRoad("Boston", 99, "Albany"))
+	assert.Equal(t, 4, m.Len())
+	assert.Equal(t, []string{"Albany", "Bangor", "Boston", "Columbus"}, m.CityNames())
+	assert.Equal(t, "Boston", m.Neighbor("Bangor", South))
+	assert.Equal(t, "", m.Neighbor("Bangor", North))
+	assert.Equal(t, "", m.Neighbor("Bogus", North))
+	var buf bytes.Buffer
+	assert.NoError(t, m.Write(&buf))
+	expected := `Albany east=Boston
+Bangor south=Boston
+Boston north=Bangor west=Albany
+Columbus
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestMapJSON(t *testing.T) {
+	m := NewMap()
+	assert.NoError(t, m.AddRoad("Boston", North, "Bangor"))
+	m.AddCity("Columbus")
+	var buf bytes.Buffer
+	assert.NoError(t, m.WriteJSON(&buf))
+	expected := `{
+  "Bangor": {
+    "south": "Boston"
+  },
+  "Boston": {
+    "north": "Bangor"
+  },
+  "Columbus": {}
+}
+`
+	assert.Equal(t, expected, buf.String())
+	m2, err := ReadMapJSON(&buf, true)
+	assert.NoError(t, err)
+	assert.Equal(t, m.CityNames(), m2.CityNames())
+	assert.Equal(t, "Boston", m2.Neighbor("Bangor", South))
+
+	m2, err = ReadMapJSON(strings.NewReader(`{"Boston":{"up":"Heaven"}}`), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Boston", m2.Neighbor("Heaven", Down))
+	_, err = ReadMapJSON(strings.NewReader(`{"Boston":{"sideways":"Bangor"}}`), false)
+	assert.Error(t, err)
+	_, err = ReadMapJSON(strings.NewReader(`{"Boston":{"north":"Bangor"},"NewYork":{"north":"Bangor"}}`), false)
+	assert.Error(t, err)
+	// same error every time no matter the order directions are in
+	for i := 0; i < 10; i++ {
+		_, err = ReadMapJSON(strings.NewReader(`{"Boston":{"right":"Albany","left":"Bangor","sideways":"Salem"}}`), false)
+		assert.EqualError(t, err, "parse error, 'left' is not a recognized direction")
+	}
+}
+
+func TestMapClone(t *testing.T) {
+	m := NewMap()
+	assert.NoError(t, m.AddRoad("Boston", North, "Bangor"))
+	cloned := m.clone()
+	cloned.cities["Boston"].destroy(cloned.cities, alien("0"), alien("1"))
+	assert.Equal(t, "Bangor", m.Neighbor("Boston", North))
+	assert.Equal(t, "", cloned.Neighbor("Boston", North))
+}
+
+func TestMapWriteOmitImplied(t *testing.T) {
+	m, err := ParseMap(strings.NewReader("Boston north=Bangor west=Albany\nLoop north=Loop\nColumbus\n"), false)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, m.WriteOmitImplied(&buf))
+	expected := `Albany east=Boston
+Bangor south=Boston
+Columbus
+Loop north=Loop
+`
+	assert.Equal(t, expected, buf.String())
+	again, err := ParseMap(&buf, false)
+	assert.NoError(t, err)
+	var original, actual bytes.Buffer
+	assert.NoError(t, m.Write(&original))
+	assert.NoError(t, again.Write(&actual))
+	assert.Equal(t, original.String(), actual.String())
+
+	oneWay, err := ParseMap(strings.NewReader("Boston north=Bangor\n"), true)
+	assert.NoError(t, err)
+	assert.EqualError(t, oneWay.WriteOmitImplied(&buf), "Boston north=Bangor has no road back, implied roads cannot be left out")
+}
@@ -0,0 +1,325 @@
+package aliens
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SkippedRoad is a road from another map format that could not be put on a
+// map because it has no direction or conflicts with another road
+type SkippedRoad struct {
+	Road
+	Reason string `json:"reason"`
+}
+
+func (s SkippedRoad) String() string {
+	return fmt.Sprintf("%s -> %s %s", s.From, s.To, s.Reason)
+}
+
+// importer builds a map from roads given in other formats, skipping roads
+// it cannot express instead of failing
+type importer struct {
+	m       *Map
+	strict  bool
+	skipped []SkippedRoad
+}
+
+func newImporter(strict bool) *importer {
+	m := NewMap()
+	m.strict = strict
+	return &importer{m: m, strict: strict}
+}
+
+// add road from one city to another, either city is added if it is not on
+// map yet. Without strict, road back in opposite direction is added too.
+func (imp *importer) add(from string, label string, to string) {
+	imp.m.AddCity(from)
+	imp.m.AddCity(to)
+	road := Road{From: from, Direction: label, To: to}
+	if label == "" {
+		imp.skip(road, "has no direction")
+		return
+	}
+	direction := directionFromLabel(strings.ToLower(label))
+	if direction < 0 {
+		imp.skip(road, fmt.Sprintf("'%s' is not a recognized direction", label))
+		return
+	}
+	road.Direction = directionLabels[direction]
+	c, neighbor := imp.m.city(from), imp.m.city(to)
+	// checked before adding so a conflict does not leave half a road
+	if existing := c.neighoringCity(direction); existing != nil && existing != neighbor {
+		imp.skip(road, fmt.Sprintf("conflicts with %s=%s", road.Direction, existing.Name))
+		return
+	}
+	back := oppositeDirection(direction)
+	if !imp.strict {
+		if existing := neighbor.neighoringCity(back); existing != nil && existing != c {
+			imp.skip(road, fmt.Sprintf("conflicts with road back %s %s=%s", to, directionLabels[back], existing.Name))
+			return
+		}
+		neighbor.addNeighbor(back, c)
+	}
+	c.addNeighbor(direction, neighbor)
+}
+
+func (imp *importer) skip(road Road, reason string) {
+	imp.skipped = append(imp.skipped, SkippedRoad{Road: road, Reason: reason})
+}
+
+// roads on map in city name then direction order
+func (m *Map) roads() []Road {
+	var roads []Road
+	for _, name := range m.CityNames() {
+		for direction, label := range directionLabels {
+			if neighbor := m.cities[name].neighoringCity(direction); neighbor != nil {
+				roads = append(roads, Road{From: name, Direction: label, To: neighbor.Name})
+			}
+		}
+	}
+	return roads
+}
+
+// ReadMapCSV reads an edge list with a header row naming the from (or
+// source), to (or target) and direction columns. Direction column is
+// optional but roads without a direction are skipped. A row without
+// a to city adds a city that may not have roads.
+// Example:
+//   from,to,direction
+//   Boston,Bangor,north
+//   Albany,,
+func ReadMapCSV(r io.Reader, strict bool) (*Map, []SkippedRoad, error) {
+	in := csv.NewReader(r)
+	in.FieldsPerRecord = -1
+	header, err := in.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("parse error, csv has no header row")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	from, to, direction := -1, -1, -1
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "from", "source":
+			from = i
+		case "to", "target":
+			to = i
+		case "direction":
+			direction = i
+		}
+	}
+	if from < 0 || to < 0 {
+		return nil, nil, fmt.Errorf("parse error, csv header needs from and to columns, got %s", strings.Join(header, ","))
+	}
+	field := func(row []string, i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	imp := newImporter(strict)
+	for {
+		row, err := in.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := in.FieldPos(0)
+		fromCity, toCity := field(row, from), field(row, to)
+		if fromCity == "" {
+			return nil, nil, fmt.Errorf("parse error on line %d, no from city", line)
+		}
+		if toCity == "" {
+			imp.m.AddCity(fromCity)
+			continue
+		}
+		imp.add(fromCity, field(row, direction), toCity)
+	}
+	return imp.m, imp.skipped, nil
+}
+
+// WriteCSV writes map in format ReadMapCSV reads, cities without roads are
+// written without a to city so they are not lost
+func (m *Map) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"from", "to", "direction"})
+	for _, name := range m.CityNames() {
+		if m.cities[name].roadsOut() == 0 {
+			out.Write([]string{name, "", ""})
+		}
+	}
+	for _, road := range m.roads() {
+		out.Write([]string{road.From, road.To, road.Direction})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// graphML is just enough of GraphML for nodes and edges with a direction
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr,omitempty"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr,omitempty"`
+}
+
+type graphMLNode struct {
+	ID string `xml:"id,attr"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+// ReadMapGraphML reads nodes as cities and edges as roads from source to
+// target. Direction of a road comes from edge data with the key whose
+// attr.name is direction, edges without one are skipped.
+func ReadMapGraphML(r io.Reader, strict bool) (*Map, []SkippedRoad, error) {
+	var doc graphML
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+	directionKey := ""
+	for _, key := range doc.Keys {
+		if strings.EqualFold(key.Name, "direction") && (key.For == "edge" || key.For == "all") {
+			directionKey = key.ID
+		}
+	}
+	imp := newImporter(strict)
+	for _, node := range doc.Graph.Nodes {
+		if node.ID == "" {
+			return nil, nil, errors.New("parse error, node without an id")
+		}
+		imp.m.AddCity(node.ID)
+	}
+	for _, edge := range doc.Graph.Edges {
+		if edge.Source == "" || edge.Target == "" {
+			return nil, nil, errors.New("parse error, edge without a source or target")
+		}
+		label := ""
+		for _, data := range edge.Data {
+			if directionKey != "" && data.Key == directionKey {
+				label = strings.TrimSpace(data.Value)
+			}
+		}
+		imp.add(edge.Source, label, edge.Target)
+	}
+	return imp.m, imp.skipped, nil
+}
+
+// WriteGraphML writes map in format ReadMapGraphML reads
+func (m *Map) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		Xmlns: graphMLNamespace,
+		Keys:  []graphMLKey{{ID: "direction", For: "edge", Name: "direction", Type: "string"}},
+	}
+	doc.Graph.EdgeDefault = "directed"
+	for _, name := range m.CityNames() {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: name})
+	}
+	for _, road := range m.roads() {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: road.From,
+			Target: road.To,
+			Data:   []graphMLData{{Key: "direction", Value: road.Direction}},
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// adjacent is a neighboring city in adjacency JSON, either just the name of
+// the city or an object with the city and the direction
+type adjacent struct {
+	To        string `json:"to"`
+	Direction string `json:"direction,omitempty"`
+}
+
+func (a *adjacent) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &a.To)
+	}
+	type plain adjacent
+	return json.Unmarshal(data, (*plain)(a))
+}
+
+// ReadMapAdjacencyJSON reads a map where each city is a key to a list of
+// neighboring cities. Neighbors given by name only have no direction and are
+// skipped.
+// Example:
+//   {"Boston": [{"to": "NewYork", "direction": "south"}, "Albany"], "Albany": []}
+func ReadMapAdjacencyJSON(r io.Reader, strict bool) (*Map, []SkippedRoad, error) {
+	var doc map[string][]adjacent
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(doc))
+	for name := range doc {
+		names = append(names, name)
+	}
+	// sorted so skipped roads are in a consistent order
+	sort.Strings(names)
+	imp := newImporter(strict)
+	for _, name := range names {
+		imp.m.AddCity(name)
+		for _, neighbor := range doc[name] {
+			if neighbor.To == "" {
+				return nil, nil, fmt.Errorf("no city name given for neighbor of %s", name)
+			}
+			imp.add(name, neighbor.Direction, neighbor.To)
+		}
+	}
+	return imp.m, imp.skipped, nil
+}
+
+// WriteAdjacencyJSON writes map in format ReadMapAdjacencyJSON reads
+func (m *Map) WriteAdjacencyJSON(w io.Writer) error {
+	doc := make(map[string][]adjacent, len(m.cities))
+	for _, name := range m.CityNames() {
+		doc[name] = []adjacent{}
+	}
+	for _, road := range m.roads() {
+		doc[road.From] = append(doc[road.From], adjacent{To: road.To, Direction: road.Direction})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,145 @@
+package aliens
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapFormatsRoundTrip(t *testing.T) {
+	in, err := os.Open("testdata/medium-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	original, err := ParseMap(in, true)
+	assert.NoError(t, err)
+	original.AddCity("Nowhere") // cities without roads are kept too
+	original.strict = true      // every format reads back strictly below
+	assert.NoError(t, original.AddRoad("Heaven", Down, "Purgatory"))
+	var expected bytes.Buffer
+	assert.NoError(t, original.Write(&expected))
+
+	formats := []struct {
+		write func(*Map, io.Writer) error
+		read  func(io.Reader, bool) (*Map, []SkippedRoad, error)
+	}{
+		{(*Map).WriteCSV, ReadMapCSV},
+		{(*Map).WriteGraphML, ReadMapGraphML},
+		{(*Map).WriteAdjacencyJSON, ReadMapAdjacencyJSON},
+		{(*Map).WriteJSON, func(r io.Reader, strict bool) (*Map, []SkippedRoad, error) {
+			m, err := ReadMapJSON(r, strict)
+			return m, nil, err
+		}},
+		{(*Map).Write, func(r io.Reader, strict bool) (*Map, []SkippedRoad, error) {
+			m, err := ParseMap(r, strict)
+			return m, nil, err
+		}},
+	}
+	for _, format := range formats {
+		var buf bytes.Buffer
+		assert.NoError(t, format.write(original, &buf))
+		m, skipped, err := format.read(&buf, true)
+		assert.NoError(t, err)
+		assert.Empty(t, skipped)
+		var actual bytes.Buffer
+		assert.NoError(t, m.Write(&actual))
+		assert.Equal(t, expected.String(), actual.String())
+	}
+}
+
+func TestMapFormatsWrite(t *testing.T) {
+	in, err := os.Open("testdata/small-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	m, err := ParseMap(in, false)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, m.WriteCSV(&buf))
+	Golden(t, *updateFlag, "testdata/small-map.csv", &buf)
+	assert.NoError(t, m.WriteGraphML(&buf))
+	Golden(t, *updateFlag, "testdata/small-map.graphml", &buf)
+	assert.NoError(t, m.WriteAdjacencyJSON(&buf))
+	Golden(t, *updateFlag, "testdata/small-map-adjacency.json", &buf)
+}
+
+func TestReadMapCSV(t *testing.T) {
+	m, skipped, err := ReadMapCSV(strings.NewReader(`source,target,direction
+Boston,Bangor,North
+Boston,NewYork,
+Boston,Paris,northeast
+Boston,Albany,north
+Albany,,
+`), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []SkippedRoad{
+		{Road{From: "Boston", To: "NewYork"}, "has no direction"},
+		{Road{From: "Boston", Direction: "northeast", To: "Paris"}, "'northeast' is not a recognized direction"},
+		{Road{From: "Boston", Direction: "north", To: "Albany"}, "conflicts with north=Bangor"},
+	}, skipped)
+	assert.Equal(t, "Bangor", m.Neighbor("Boston", North))
+	assert.Equal(t, "Boston", m.Neighbor("Bangor", South))
+	assert.Equal(t, []string{"Albany", "Bangor", "Boston", "NewYork", "Paris"}, m.CityNames())
+	assert.Equal(t, "Boston -> Paris 'northeast' is not a recognized direction", skipped[1].String())
+
+	// road back would conflict
+	_, skipped, err = ReadMapCSV(strings.NewReader("from,to,direction\nBoston,Bangor,north\nAlbany,Bangor,north\n"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "conflicts with road back Bangor south=Boston", skipped[0].Reason)
+	_, skipped, err = ReadMapCSV(strings.NewReader("from,to,direction\nBoston,Bangor,north\nAlbany,Bangor,north\n"), true)
+	assert.NoError(t, err)
+	assert.Empty(t, skipped)
+
+	_, _, err = ReadMapCSV(strings.NewReader("city,direction\n"), false)
+	assert.EqualError(t, err, "parse error, csv header needs from and to columns, got city,direction")
+	_, _, err = ReadMapCSV(strings.NewReader("from,to\n,Boston\n"), false)
+	assert.EqualError(t, err, "parse error on line 2, no from city")
+	_, _, err = ReadMapCSV(strings.NewReader(""), false)
+	assert.EqualError(t, err, "parse error, csv has no header row")
+}
+
+func TestReadMapGraphML(t *testing.T) {
+	m, skipped, err := ReadMapGraphML(strings.NewReader(`<?xml version="1.0"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="d0" for="edge" attr.name="direction" attr.type="string"/>
+  <key id="d1" for="edge" attr.name="weight" attr.type="double"/>
+  <graph edgedefault="undirected">
+    <node id="Boston"/>
+    <node id="Bangor"/>
+    <node id="Paris"/>
+    <edge source="Boston" target="Bangor"><data key="d0">north</data><data key="d1">2.5</data></edge>
+    <edge source="Boston" target="Paris"><data key="d1">5000</data></edge>
+  </graph>
+</graphml>`), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []SkippedRoad{{Road{From: "Boston", To: "Paris"}, "has no direction"}}, skipped)
+	assert.Equal(t, "Bangor", m.Neighbor("Boston", North))
+	assert.Equal(t, 3, m.Len())
+
+	_, _, err = ReadMapGraphML(strings.NewReader(`<graphml><graph><edge source="Boston"/></graph></graphml>`), false)
+	assert.EqualError(t, err, "parse error, edge without a source or target")
+}
+
+func TestReadMapAdjacencyJSON(t *testing.T) {
+	m, skipped, err := ReadMapAdjacencyJSON(strings.NewReader(`{
+		"Boston": [{"to": "Bangor", "direction": "north"}, "Paris", {"to": "Rome", "direction": "up"}],
+		"Albany": []
+	}`), true)
+	assert.NoError(t, err)
+	assert.Equal(t, []SkippedRoad{
+		{Road{From: "Boston", To: "Paris"}, "has no direction"},
+	}, skipped)
+	assert.Equal(t, "Bangor", m.Neighbor("Boston", North))
+	assert.Equal(t, "Rome", m.Neighbor("Boston", Up))
+	assert.Equal(t, "", m.Neighbor("Bangor", South))
+	assert.Equal(t, 5, m.Len())
+
+	_, _, err = ReadMapAdjacencyJSON(strings.NewReader(`{"Boston": [{"direction": "north"}]}`), true)
+	assert.EqualError(t, err, "no city name given for neighbor of Boston")
+}
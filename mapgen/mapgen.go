@@ -0,0 +1,230 @@
+// Package mapgen procedurally generates city maps of various shapes for
+// testing invasions on maps larger than anyone would want to write by hand.
+package mapgen
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dhubler/aliens"
+)
+
+// Topologies that Generate understands
+var Topologies = []string{
+	"grid", "torus", "tree", "planar", "ring",
+}
+
+// Generate builds a map from the name of a topology.  The meaning of size depends
+// on topology
+//   grid, torus, planar : size x size cities
+//   tree                : depth of tree
+//   ring                : number of cities in ring
+// seed is only used by topologies with random layout, tree and planar.
+func Generate(topology string, size int, seed int64) (*aliens.Map, error) {
+	switch topology {
+	case "grid":
+		return Grid(size, size)
+	case "torus":
+		return Torus(size, size)
+	case "tree":
+		return Tree(size, seed)
+	case "planar":
+		return Planar(size, size, seed)
+	case "ring":
+		return Ring(size)
+	}
+	return nil, fmt.Errorf("unrecognized topology '%s'", topology)
+}
+
+// Grid builds cities in rows and columns where each city connects to its
+// adjacent cities.  Row 0 is furthest north and column 0 is furthest west.
+func Grid(width, height int) (*aliens.Map, error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("grid must be at least 1x1, got %dx%d", width, height)
+	}
+	m := aliens.NewMap()
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			m.AddCity(gridName(row, col))
+			if col > 0 {
+				if err := m.AddRoad(gridName(row, col-1), aliens.East, gridName(row, col)); err != nil {
+					return nil, err
+				}
+			}
+			if row > 0 {
+				if err := m.AddRoad(gridName(row-1, col), aliens.South, gridName(row, col)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// Torus is a grid where the east edge wraps around to west edge and the south
+// edge wraps around to the north edge so there are no edges at all.
+func Torus(width, height int) (*aliens.Map, error) {
+	if width < 2 || height < 2 {
+		return nil, fmt.Errorf("torus must be at least 2x2, got %dx%d", width, height)
+	}
+	m, err := Grid(width, height)
+	if err != nil {
+		return nil, err
+	}
+	for row := 0; row < height; row++ {
+		if err := m.AddRoad(gridName(row, width-1), aliens.East, gridName(row, 0)); err != nil {
+			return nil, err
+		}
+	}
+	for col := 0; col < width; col++ {
+		if err := m.AddRoad(gridName(height-1, col), aliens.South, gridName(0, col)); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Ring builds cities in a single loop going east.  If you go east enough, you
+// get back to where you started.
+func Ring(size int) (*aliens.Map, error) {
+	if size < 2 {
+		return nil, fmt.Errorf("ring must have at least 2 cities, got %d", size)
+	}
+	m := aliens.NewMap()
+	for i := 0; i < size; i++ {
+		next := (i + 1) % size
+		if err := m.AddRoad(ringName(i), aliens.East, ringName(next)); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Tree builds a tree from a single root city where each city has a random set
+// of child cities in the directions that are not taken by its parent.  City
+// names describe path from root, so "tne" is east of north of root.
+func Tree(depth int, seed int64) (*aliens.Map, error) {
+	if depth < 0 {
+		return nil, fmt.Errorf("tree depth cannot be negative, got %d", depth)
+	}
+	rnd := aliens.NewPCG(seed)
+	m := aliens.NewMap()
+	m.AddCity("t")
+	if err := treeNest(m, rnd, "t", depth); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+var directionInitials = []string{"n", "s", "e", "w"}
+
+// recursive function to help generate tree
+func treeNest(m *aliens.Map, rnd aliens.Random, parent string, levels int) error {
+	if levels == 0 {
+		return nil
+	}
+	var free []int
+	for direction := range directionInitials {
+		if m.Neighbor(parent, direction) == "" {
+			free = append(free, direction)
+		}
+	}
+	shuffle(rnd, len(free), func(i, j int) { free[i], free[j] = free[j], free[i] })
+	// at least one child so tree always reaches given depth
+	children := free[:1+rnd.Intn(len(free))]
+	sort.Ints(children)
+	for _, direction := range children {
+		child := parent + directionInitials[direction]
+		if err := m.AddRoad(parent, direction, child); err != nil {
+			return err
+		}
+		if err := treeNest(m, rnd, child, levels-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Planar places cities on a grid but only builds a random subset of roads
+// between adjacent cities.  Roads never cross and every city is reachable from
+// every other city.
+func Planar(width, height int, seed int64) (*aliens.Map, error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("planar map must be at least 1x1, got %dx%d", width, height)
+	}
+	type road struct {
+		fromRow, fromCol int
+		direction        int
+		toRow, toCol     int
+	}
+	var roads []road
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			if col+1 < width {
+				roads = append(roads, road{row, col, aliens.East, row, col + 1})
+			}
+			if row+1 < height {
+				roads = append(roads, road{row, col, aliens.South, row + 1, col})
+			}
+		}
+	}
+	rnd := aliens.NewPCG(seed)
+	shuffle(rnd, len(roads), func(i, j int) { roads[i], roads[j] = roads[j], roads[i] })
+
+	// pass 1 : random spanning tree guarantees every city is reachable
+	// pass 2 : about half of the remaining roads are built
+	group := make([]int, width*height)
+	for i := range group {
+		group[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if group[i] != i {
+			group[i] = find(group[i])
+		}
+		return group[i]
+	}
+	m := aliens.NewMap()
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			m.AddCity(gridName(row, col))
+		}
+	}
+	var extra []road
+	for _, r := range roads {
+		a, b := find(r.fromRow*width+r.fromCol), find(r.toRow*width+r.toCol)
+		if a == b {
+			extra = append(extra, r)
+			continue
+		}
+		group[a] = b
+		if err := m.AddRoad(gridName(r.fromRow, r.fromCol), r.direction, gridName(r.toRow, r.toCol)); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range extra {
+		if rnd.Intn(2) == 0 {
+			continue
+		}
+		if err := m.AddRoad(gridName(r.fromRow, r.fromCol), r.direction, gridName(r.toRow, r.toCol)); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// shuffle is a Fisher-Yates shuffle that only needs Intn so maps from the same
+// seed are the same no matter which Go release they are generated with
+func shuffle(rnd aliens.Random, n int, swap func(i, j int)) {
+	for i := n - 1; i > 0; i-- {
+		swap(i, rnd.Intn(i+1))
+	}
+}
+
+func gridName(row, col int) string {
+	return fmt.Sprintf("r%dc%d", row, col)
+}
+
+func ringName(i int) string {
+	return fmt.Sprintf("ring%d", i)
+}
@@ -0,0 +1,85 @@
+package mapgen
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/dhubler/aliens"
+	"github.com/stretchr/testify/assert"
+)
+
+var updateFlag = flag.Bool("update", false, "update expected golden file(s)")
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		topology string
+		size     int
+		cities   int
+	}{
+		{topology: "grid", size: 3, cities: 9},
+		{topology: "torus", size: 3, cities: 9},
+		{topology: "tree", size: 3},
+		{topology: "planar", size: 4, cities: 16},
+		{topology: "ring", size: 5, cities: 5},
+	}
+	for _, test := range tests {
+		m, err := Generate(test.topology, test.size, 99)
+		if err != nil {
+			t.Fatal(err, test.topology)
+		}
+		if test.cities > 0 {
+			assert.Equal(t, test.cities, m.Len(), test.topology)
+		}
+		var buf bytes.Buffer
+		assert.NoError(t, m.Write(&buf))
+
+		// generated maps have to be readable by strict parse which proves every
+		// road has a road back
+		reparsed, err := aliens.ParseMap(bytes.NewReader(buf.Bytes()), true)
+		assert.NoError(t, err, test.topology)
+		var rebuf bytes.Buffer
+		assert.NoError(t, reparsed.Write(&rebuf))
+		assert.Equal(t, buf.String(), rebuf.String(), test.topology)
+
+		golden := fmt.Sprintf("testdata/%s.golden", test.topology)
+		aliens.Golden(t, *updateFlag, golden, &buf)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	_, err := Generate("blob", 3, 0)
+	assert.Error(t, err)
+	_, err = Generate("grid", 0, 0)
+	assert.Error(t, err)
+	_, err = Generate("torus", 1, 0)
+	assert.Error(t, err)
+	_, err = Generate("ring", 1, 0)
+	assert.Error(t, err)
+	_, err = Generate("tree", -1, 0)
+	assert.Error(t, err)
+}
+
+func TestPlanarConnected(t *testing.T) {
+	m, err := Planar(6, 4, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	visited := map[string]bool{}
+	pending := []string{gridName(0, 0)}
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		if visited[name] {
+			continue
+		}
+		visited[name] = true
+		for _, direction := range []int{aliens.North, aliens.South, aliens.East, aliens.West} {
+			if neighbor := m.Neighbor(name, direction); neighbor != "" {
+				pending = append(pending, neighbor)
+			}
+		}
+	}
+	assert.Equal(t, 24, len(visited))
+}
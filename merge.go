@@ -0,0 +1,137 @@
+package aliens
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MapConflict is a road from a map file that could not be put on map because
+// city already has a road in that direction to another city
+type MapConflict struct {
+	Road           Road   `json:"road"`
+	Source         string `json:"source"` // file and line road is from
+	Existing       Road   `json:"existing"`
+	ExistingSource string `json:"existingSource"`
+}
+
+func (c MapConflict) String() string {
+	return fmt.Sprintf("%s: %s %s=%s conflicts with %s=%s from %s", c.Source, quoteName(c.Road.From), c.Road.Direction, quoteName(c.Road.To),
+		c.Existing.Direction, quoteName(c.Existing.To), c.ExistingSource)
+}
+
+// MapConflicts is every conflicting road found reading map files
+type MapConflicts []MapConflict
+
+func (c MapConflicts) Error() string {
+	lines := make([]string, len(c))
+	for i, conflict := range c {
+		lines[i] = conflict.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseMapFile reads a map file following any include lines in it. Files to
+// include are relative to the directory of the file including them. Roads
+// that conflict with each other are all returned as MapConflicts saying which
+// file and line each road is from. When any file is strict, header of map
+// has a #!strict directive so map is read back the same when written.
+// Example:
+//   # northeast.txt
+//   include maine.txt
+//   Boston north=Bangor
+func ParseMapFile(fname string, strict bool) (*Map, error) {
+	files := newMapFiles(strict)
+	header, err := files.read(fname)
+	if err != nil {
+		return nil, err
+	}
+	cities, err := buildCities(files.refs)
+	if err != nil {
+		return nil, err
+	}
+	return &Map{cities: cities, header: files.directives(header)}, nil
+}
+
+// MergeMapFiles combines maps in files into one map, the same as a map file
+// that only includes each of them. Comments at top of files are not kept but
+// like ParseMapFile, header has a #!strict directive when any file is strict.
+func MergeMapFiles(fnames []string, strict bool) (*Map, error) {
+	files := newMapFiles(strict)
+	for _, fname := range fnames {
+		if _, err := files.read(fname); err != nil {
+			return nil, err
+		}
+	}
+	cities, err := buildCities(files.refs)
+	if err != nil {
+		return nil, err
+	}
+	return &Map{cities: cities, header: files.directives(nil)}, nil
+}
+
+// mapFiles gathers cities from map files and the files they include
+type mapFiles struct {
+	strict    bool
+	anyStrict bool // some file was read strictly so roads might only go one way
+	refs      []*cityRef
+	done      map[string]bool // files already read, they are only read once
+	reading   []string        // files including file being read to catch cycles
+}
+
+func newMapFiles(strict bool) *mapFiles {
+	return &mapFiles{strict: strict, refs: make([]*cityRef, 0), done: make(map[string]bool)}
+}
+
+// read map file after files it includes and give back its header without
+// the includes and directives that were for this file alone
+func (files *mapFiles) read(fname string) ([]string, error) {
+	fname = filepath.Clean(fname)
+	for _, including := range files.reading {
+		if including == fname {
+			return nil, fmt.Errorf("include cycle %s -> %s", strings.Join(files.reading, " -> "), fname)
+		}
+	}
+	if files.done[fname] {
+		return nil, nil
+	}
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	mf, err := readMapFile(f, fname, files.strict)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	files.reading = append(files.reading, fname)
+	for _, include := range mf.includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(fname), include)
+		}
+		if _, err = files.read(include); err != nil {
+			return nil, err
+		}
+	}
+	files.reading = files.reading[:len(files.reading)-1]
+	files.done[fname] = true
+	files.anyStrict = files.anyStrict || mf.strict
+	files.refs = append(files.refs, mf.refs...)
+	var header []string
+	for _, line := range mf.header {
+		if _, isInclude := parseInclude(line); !isInclude && !strings.HasPrefix(line, "#!") {
+			header = append(header, line)
+		}
+	}
+	return trimBlankLines(header), nil
+}
+
+// directives a header needs for map of every file read to be read back the
+// same. Directions are declared when map is written if they need to be.
+func (files *mapFiles) directives(header []string) []string {
+	if !files.anyStrict {
+		return header
+	}
+	return append([]string{"#!strict"}, header...)
+}
@@ -0,0 +1,109 @@
+package aliens
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMapFile(t *testing.T) {
+	m, err := ParseMapFile("testdata/regions/new-england.txt", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Albany", "Bangor", "Boston", "New York", "Presque"}, m.CityNames())
+	assert.Empty(t, m.Includes())
+	var buf bytes.Buffer
+	assert.NoError(t, m.Write(&buf))
+	expected := `# new england
+
+Albany south="New York" east=Boston
+Bangor north=Presque south=Boston
+Boston north=Bangor west=Albany
+"New York" north=Albany
+Presque south=Bangor
+`
+	assert.Equal(t, expected, buf.String())
+
+	_, err = ParseMapFile("testdata/regions/cycle-a.txt", false)
+	assert.EqualError(t, err, "include cycle testdata/regions/cycle-a.txt -> testdata/regions/cycle-b.txt -> testdata/regions/cycle-a.txt")
+}
+
+func TestMergeMapFiles(t *testing.T) {
+	m, err := MergeMapFiles([]string{"testdata/regions/maine.txt", "testdata/small-map.txt"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Presque", m.Neighbor("Bangor", North))
+	assert.Equal(t, "Trenton", m.Neighbor("NewYork", South))
+
+	_, err = MergeMapFiles([]string{"testdata/regions/new-england.txt", "testdata/regions/conflict.txt"}, false)
+	var conflicts MapConflicts
+	assert.True(t, errors.As(err, &conflicts))
+	assert.Equal(t, MapConflicts{
+		{
+			Road:           Road{From: "Bangor", Direction: "north", To: "Portland"},
+			Source:         "testdata/regions/conflict.txt:1",
+			Existing:       Road{From: "Bangor", Direction: "north", To: "Presque"},
+			ExistingSource: "testdata/regions/maine.txt:1",
+		},
+		{
+			Road:           Road{From: "Boston", Direction: "west", To: "Worcester"},
+			Source:         "testdata/regions/conflict.txt:2",
+			Existing:       Road{From: "Boston", Direction: "west", To: "Albany"},
+			ExistingSource: "testdata/regions/new-england.txt:5",
+		},
+	}, conflicts)
+	assert.Equal(t, "testdata/regions/conflict.txt:1: Bangor north=Portland conflicts with north=Presque from testdata/regions/maine.txt:1", conflicts[0].String())
+
+	// road back is what conflicts
+	_, err = MergeMapFiles([]string{"testdata/regions/maine.txt", "testdata/bad-map.txt"}, false)
+	assert.EqualError(t, err, "testdata/bad-map.txt:2: NewYork north=NewHaven conflicts with north=Boston from testdata/bad-map.txt:1")
+
+	_, err = MergeMapFiles([]string{"testdata/regions/missing.txt"}, false)
+	assert.Error(t, err)
+}
+
+func TestMergeStrictMapFiles(t *testing.T) {
+	for _, fnames := range [][]string{
+		{"testdata/regions/one-way.txt"},
+		{"testdata/regions/maine.txt", "testdata/regions/one-way.txt"},
+	} {
+		m, err := MergeMapFiles(fnames, false)
+		assert.NoError(t, err)
+		var buf bytes.Buffer
+		assert.NoError(t, m.Write(&buf))
+		assert.True(t, strings.HasPrefix(buf.String(), "#!strict\n"), buf.String())
+		again, err := ParseMap(&buf, false)
+		assert.NoError(t, err)
+		assert.True(t, DiffMaps(m, again).Same(), "%v", fnames)
+	}
+
+	m, err := ParseMapFile("testdata/regions/one-way.txt", false)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, m.Write(&buf))
+	assert.Equal(t, "#!strict\n# roads that only go one way\n\nA north=B\nB north=A\n", buf.String())
+}
+
+func TestParseMapIncludes(t *testing.T) {
+	in := "include maine.txt\nBoston north=Bangor\n"
+	m, err := ParseMap(strings.NewReader(in), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"maine.txt"}, m.Includes())
+	var buf bytes.Buffer
+	assert.NoError(t, m.Write(&buf))
+	assert.Equal(t, "include maine.txt\n\nBangor south=Boston\nBoston north=Bangor\n", buf.String())
+
+	_, err = NewInvasion(Options{CityMap: m})
+	assert.EqualError(t, err, "parse error, include maine.txt is only followed when map is read from a file")
+	_, err = NewInvasion(Options{CityMapInput: strings.NewReader(in)})
+	assert.EqualError(t, err, "parse error, include maine.txt is only followed when map is read from a file")
+
+	_, err = ParseMap(strings.NewReader("Boston\ninclude maine.txt\n"), false)
+	assert.EqualError(t, err, "parse error, include maine.txt must come before first city")
+
+	// city named include is still a city
+	m, err = ParseMap(strings.NewReader("include north=Boston\n"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Boston", "include"}, m.CityNames())
+}
@@ -2,29 +2,95 @@ package aliens
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // parse returns sorted array of cities by parsing input stream acoording to
 // a specific format. see README.md for full spec
 // Example:
-//   Boston south=NewYork west=Albany
-//   Albany east=Boston
+//   #!directions north,south,east,west,up,down
+//   include west.txt
+//   Boston south=NewYork west=Albany # comment
+//   Albany east=Boston up=Heaven
 //   ..
 func parse(r io.Reader, strict bool) (map[string]*city, error) {
+	mf, err := readMapFile(r, "", strict)
+	if err != nil {
+		return nil, err
+	}
+	if err = unfollowed(mf.includes); err != nil {
+		return nil, err
+	}
+	return buildCities(mf.refs)
+}
+
+// mapFile is what one map has before its cities are built
+type mapFile struct {
+	refs     []*cityRef
+	header   []string // comment lines at top of map before first city
+	includes []string // files named by include lines
+	comments []int    // line numbers of comments from first city on
+	strict   bool     // parsed strictly, by option or directive
+}
+
+// readMapFile reads city lines of a map along with the comments, directives
+// and includes at top of it. Source is the name of the map file so cities can
+// be traced back to it, empty when map is not from a file.
+func readMapFile(r io.Reader, source string, strict bool) (*mapFile, error) {
 	lines := bufio.NewReader(r)
-	refs := make([]*cityRef, 0)
-	for {
+	mf := &mapFile{refs: make([]*cityRef, 0)}
+	declared := compassLabels
+	for lineNum := 1; ; lineNum++ {
 		line, err := lines.ReadString('\n')
 		if line != "" {
+			trimmed := strings.TrimSpace(line)
+			if include, isInclude := parseInclude(trimmed); isInclude {
+				if len(mf.refs) > 0 {
+					return nil, fmt.Errorf("parse error, include %s must come before first city", include)
+				}
+				mf.header = append(mf.header, trimmed)
+				mf.includes = append(mf.includes, include)
+				continue
+			}
+			if len(mf.refs) == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "#")) {
+				mf.header = append(mf.header, trimmed)
+				if strings.HasPrefix(trimmed, "#!") {
+					if err := parseDirective(trimmed, &strict, &declared); err != nil {
+						return nil, err
+					}
+				}
+				continue
+			}
+			if strings.HasPrefix(trimmed, "#!") {
+				return nil, fmt.Errorf("parse error, directive '%s' must come before first city", trimmed)
+			}
 			ref, err := parseCityRef(line)
 			if err != nil {
 				return nil, err
 			}
+			if strings.Contains(line, "#") && hasComment(line) {
+				mf.comments = append(mf.comments, lineNum)
+			}
 			if ref != nil {
-				refs = append(refs, ref)
+				if len(mf.refs) == 0 {
+					if err := checkDirections(declared, strict); err != nil {
+						return nil, err
+					}
+				}
+				if err := ref.checkDirections(declared); err != nil {
+					return nil, err
+				}
+				ref.strict = strict
+				if source != "" {
+					ref.source = fmt.Sprintf("%s:%d", source, lineNum)
+				}
+				mf.refs = append(mf.refs, ref)
 			}
 		}
 		if err != nil {
@@ -34,6 +100,20 @@ func parse(r io.Reader, strict bool) (map[string]*city, error) {
 			return nil, err
 		}
 	}
+	if len(mf.refs) == 0 {
+		if err := checkDirections(declared, strict); err != nil {
+			return nil, err
+		}
+	}
+	mf.header = trimBlankLines(mf.header)
+	mf.strict = strict
+	return mf, nil
+}
+
+// buildCities connects cities as refs say to. When refs are from files, every
+// road that conflicts with another is returned as MapConflicts, otherwise the
+// first conflict is the error.
+func buildCities(refs []*cityRef) (map[string]*city, error) {
 	cities := make(map[string]*city, len(refs))
 	// pass 1 : make cities
 	for _, ref := range refs {
@@ -54,7 +134,32 @@ func parse(r io.Reader, strict bool) (map[string]*city, error) {
 		}
 	}
 	// pass 2 : build cities pointers in all directions
-	//var directionLabels = []string{"north", "south", "east", "west"}
+	type roadKey struct {
+		city      string
+		direction int
+	}
+	givenBy := make(map[roadKey]*cityRef)
+	var conflicts MapConflicts
+	add := func(ref *cityRef, c *city, direction int, neighbor *city) (bool, error) {
+		key := roadKey{c.Name, direction}
+		existing := c.neighoringCity(direction)
+		if err := c.addNeighbor(direction, neighbor); err != nil {
+			if ref.source == "" {
+				return false, err
+			}
+			conflicts = append(conflicts, MapConflict{
+				Road:           Road{From: c.Name, Direction: directionLabels[direction], To: neighbor.Name},
+				Source:         ref.source,
+				Existing:       Road{From: c.Name, Direction: directionLabels[direction], To: existing.Name},
+				ExistingSource: givenBy[key].source,
+			})
+			return false, nil
+		}
+		if existing == nil {
+			givenBy[key] = ref
+		}
+		return true, nil
+	}
 	for _, ref := range refs {
 		city := cities[ref.Name]
 		for direction := range directions {
@@ -63,71 +168,264 @@ func parse(r io.Reader, strict bool) (map[string]*city, error) {
 				continue
 			}
 			neighbor := cities[neighborName]
-
-			if strict {
-				// all paths must be explicity defined
-				if err := city.addNeighbor(direction, neighbor); err != nil {
-					return nil, err
-				}
-			} else {
-				// assume every path in one direction implies path back in opposite direction
-				if err := city.addNeighborBidiectional(direction, neighbor); err != nil {
+			added, err := add(ref, city, direction, neighbor)
+			if err != nil {
+				return nil, err
+			}
+			// all paths must be explicity defined when strict, otherwise
+			// assume every path in one direction implies path back in
+			// opposite direction
+			if added && !ref.strict {
+				if _, err = add(ref, neighbor, oppositeDirection(direction), city); err != nil {
 					return nil, err
 				}
 			}
 		}
 	}
-
+	if len(conflicts) > 0 {
+		return nil, conflicts
+	}
 	return cities, nil
 }
 
+// parseInclude gives file named by an include line like
+//   include "north east.txt"
+func parseInclude(line string) (string, bool) {
+	fields, _, err := splitFields(line)
+	if err != nil || len(fields) != 2 || fields[0].raw != "include" || len(fields[1].parts) != 1 {
+		return "", false
+	}
+	return fields[1].parts[0], true
+}
+
+// unfollowed is an error when map includes other maps that were not read
+func unfollowed(includes []string) error {
+	if len(includes) == 0 {
+		return nil
+	}
+	return fmt.Errorf("parse error, include %s is only followed when map is read from a file", includes[0])
+}
+
+// parseDirective applies a #! line from the header of a map. Like any line,
+// it can end with a comment.
+func parseDirective(line string, strict *bool, declared *[]string) error {
+	directive, _, _ := strings.Cut(strings.TrimPrefix(line, "#!"), "#")
+	name, value, _ := strings.Cut(strings.TrimSpace(directive), " ")
+	value = strings.TrimSpace(value)
+	switch name {
+	case "strict":
+		if value != "" {
+			return fmt.Errorf("parse error, #!strict takes no value, got '%s'", value)
+		}
+		*strict = true
+	case "directions":
+		var labels []string
+		for _, label := range strings.Split(value, ",") {
+			label = strings.TrimSpace(label)
+			if directionFromLabel(label) < 0 {
+				return fmt.Errorf("parse error, '%s' in #!directions is not a recognized direction", label)
+			}
+			labels = append(labels, label)
+		}
+		*declared = labels
+	default:
+		return fmt.Errorf("parse error, unrecognized directive '%s'", line)
+	}
+	return nil
+}
+
+// headerDirectives are what directives in header of a map say. Header was
+// checked when map was parsed so directives are known to be valid. Declared
+// is nil without a #!directions directive.
+func headerDirectives(header []string) (strict bool, declared []string) {
+	for _, line := range header {
+		if strings.HasPrefix(line, "#!") {
+			parseDirective(line, &strict, &declared)
+		}
+	}
+	return strict, declared
+}
+
+// checkDirections makes sure roads back that a parse that is not strict adds
+// are in declared directions too
+func checkDirections(declared []string, strict bool) error {
+	if strict {
+		return nil
+	}
+	for _, label := range declared {
+		back := directionLabels[oppositeDirection(directionFromLabel(label))]
+		if !containsLabel(declared, back) {
+			return fmt.Errorf("parse error, #!directions has %s without %s for roads back, use #!strict if roads only go one way", label, back)
+		}
+	}
+	return nil
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, candidate := range labels {
+		if candidate == label {
+			return true
+		}
+	}
+	return false
+}
+
+// trimBlankLines from start and end
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
 func parseCityRef(line string) (*cityRef, error) {
-	segs := strings.Split(strings.Trim(line, " \n"), " ")
-	if len(segs) < 1 {
-		return nil, fmt.Errorf("parse error, no city defined in '%s'", line)
+	fields, _, err := splitFields(line)
+	if err != nil {
+		return nil, err
 	}
-	name := segs[0]
 	// opinion: ignore and allow blank lines
-	if name == "" {
+	if len(fields) == 0 {
 		return nil, nil
 	}
+	// opinion: = in a city name is just part of the name
+	name := strings.Join(fields[0].parts, "=")
+	if name == "" {
+		return nil, fmt.Errorf("parse error, no city defined in '%s'", strings.TrimSpace(line))
+	}
 	ref := cityRef{Name: name}
-	for i := 1; i < len(segs); i++ {
-		directionAndCity := strings.Split(segs[i], "=")
-		if len(directionAndCity) != 2 {
-			return nil, fmt.Errorf("parse error, invalid direction=city '%s'", segs[i])
+	for _, f := range fields[1:] {
+		if len(f.parts) != 2 {
+			return nil, fmt.Errorf("parse error, invalid direction=city '%s'", f.raw)
 		}
-		if directionAndCity[1] == "" {
-			return nil, fmt.Errorf("no city name given for '%s'  %s=", segs[i], directionAndCity[1])
+		label, neighbor := f.parts[0], f.parts[1]
+		if neighbor == "" {
+			return nil, fmt.Errorf("no city name given for '%s'", f.raw)
 		}
 		// opinion: allows for redundant directions and takes last value
-		switch directionAndCity[0] {
+		switch label {
 		case "north":
-			ref.North = directionAndCity[1]
+			ref.North = neighbor
 		case "south":
-			ref.South = directionAndCity[1]
+			ref.South = neighbor
 		case "east":
-			ref.East = directionAndCity[1]
+			ref.East = neighbor
 		case "west":
-			ref.West = directionAndCity[1]
+			ref.West = neighbor
+		case "up":
+			ref.Up = neighbor
+		case "down":
+			ref.Down = neighbor
 		default:
-			return nil, fmt.Errorf("parse error, '%s' is not a recognized direction", directionAndCity[0])
+			return nil, fmt.Errorf("parse error, '%s' is not a recognized direction", label)
 		}
 	}
 	return &ref, nil
 }
 
+// field is text between whitespace on a line split into parts at each = that
+// is not quoted. Quoted parts are unquoted.
+type field struct {
+	raw   string
+	parts []string
+}
+
+// splitFields of a line up to a comment, if it has one. Any amount of
+// whitespace separates fields and city names with whitespace, # or = in them
+// can be quoted with the same quotes and escapes as a Go string like
+// "New York" or "S\u00e3o Paulo".
+func splitFields(line string) ([]field, bool, error) {
+	var fields []field
+	var part strings.Builder
+	var f *field
+	start := 0 // of field being split
+	endField := func(end int) {
+		if f != nil {
+			f.parts = append(f.parts, part.String())
+			f.raw = line[start:end]
+			fields = append(fields, *f)
+			f = nil
+			part.Reset()
+		}
+	}
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if r == '#' || unicode.IsSpace(r) {
+			endField(i)
+			if r == '#' {
+				// comment is rest of line
+				return fields, true, nil
+			}
+			i += size
+			continue
+		}
+		if f == nil {
+			f = &field{}
+			start = i
+		}
+		switch r {
+		case '"':
+			quoted, err := strconv.QuotedPrefix(line[i:])
+			if err != nil {
+				return nil, false, fmt.Errorf("parse error, invalid quoted name in '%s'", strings.TrimSpace(line[start:]))
+			}
+			// cannot fail, prefix is already known to be quoted correctly
+			unquoted, _ := strconv.Unquote(quoted)
+			part.WriteString(unquoted)
+			i += len(quoted)
+			continue
+		case '=':
+			f.parts = append(f.parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+		i += size
+	}
+	endField(len(line))
+	return fields, false, nil
+}
+
+// hasComment is true when line has a # that is not part of a quoted name
+func hasComment(line string) bool {
+	_, comment, err := splitFields(line)
+	return err == nil && comment
+}
+
+// quoteName if it would not be read back as the same name otherwise
+func quoteName(name string) string {
+	if name == "" || !utf8.ValidString(name) {
+		return strconv.Quote(name)
+	}
+	for _, r := range name {
+		if r == '"' || r == '#' || r == '=' || unicode.IsSpace(r) || !strconv.IsPrint(r) {
+			return strconv.Quote(name)
+		}
+	}
+	return name
+}
+
 func dump(wtr io.Writer, cities map[string]*city) error {
+	return dumpWithHeader(wtr, nil, cities)
+}
+
+// dumpWithHeader writes comment lines that were at top of map before cities
+func dumpWithHeader(wtr io.Writer, header []string, cities map[string]*city) error {
+	if err := writeHeader(wtr, header, cities); err != nil {
+		return err
+	}
 	names := cityNames(cities)
 	for _, name := range names {
 		city := cities[name]
-		if _, err := fmt.Fprint(wtr, name); err != nil {
+		if _, err := fmt.Fprint(wtr, quoteName(name)); err != nil {
 			return err
 		}
 		for direction, directionLabel := range directionLabels {
 			neighbor := city.neighoringCity(direction)
 			if neighbor != nil {
-				if _, err := fmt.Fprintf(wtr, " %s=%s", directionLabel, neighbor.Name); err != nil {
+				if _, err := fmt.Fprintf(wtr, " %s=%s", directionLabel, quoteName(neighbor.Name)); err != nil {
 					return err
 				}
 			}
@@ -138,3 +436,61 @@ func dump(wtr io.Writer, cities map[string]*city) error {
 	}
 	return nil
 }
+
+// dumpOmitImplied is like dump but leaves out roads that a default parse
+// would add back on its own. Of two roads between cities, the one from the city
+// first in sorted order is kept. Cities are only given their own line if they
+// have roads left to write or would otherwise be missing.
+func dumpOmitImplied(wtr io.Writer, header []string, cities map[string]*city) error {
+	if strict, _ := headerDirectives(header); strict {
+		return errors.New("map is strict, implied roads cannot be left out")
+	}
+	names := cityNames(cities)
+	roads := make(map[string][]string, len(names))
+	mentioned := make(map[string]bool, len(names))
+	for _, name := range names {
+		city := cities[name]
+		for direction, directionLabel := range directionLabels {
+			neighbor := city.neighoringCity(direction)
+			if neighbor == nil {
+				continue
+			}
+			back := oppositeDirection(direction)
+			if neighbor.neighoringCity(back) != city {
+				return fmt.Errorf("%s %s=%s has no road back, implied roads cannot be left out", name, directionLabel, neighbor.Name)
+			}
+			if neighbor.Name < name || (neighbor == city && back < direction) {
+				continue
+			}
+			roads[name] = append(roads[name], fmt.Sprintf("%s=%s", directionLabel, quoteName(neighbor.Name)))
+			mentioned[neighbor.Name] = true
+		}
+	}
+	if err := writeHeader(wtr, header, cities); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if len(roads[name]) == 0 && mentioned[name] {
+			continue
+		}
+		line := append([]string{quoteName(name)}, roads[name]...)
+		if _, err := fmt.Fprintln(wtr, strings.Join(line, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHeader followed by a blank line if there is one. Maps with roads that
+// are not in compass directions get a #!directions directive so they can be
+// parsed again, unless header already has one.
+func writeHeader(wtr io.Writer, header []string, cities map[string]*city) error {
+	if _, declared := headerDirectives(header); declared == nil && !compassOnly(cities) {
+		header = append([]string{"#!directions " + strings.Join(directionLabels, ",")}, header...)
+	}
+	if len(header) == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(wtr, "%s\n\n", strings.Join(header, "\n"))
+	return err
+}
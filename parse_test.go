@@ -85,10 +85,43 @@ func TestParseLine(t *testing.T) {
 			line:     "Foo north=a south=b east=c west=d",
 			expected: &cityRef{Name: "Foo", North: "a", South: "b", East: "c", West: "d"},
 		},
+		{
+			line:     "  Foo \t north=Bar   south=Goo \r\n",
+			expected: &cityRef{Name: "Foo", North: "Bar", South: "Goo"},
+		},
+		{
+			line:     `"New York" north="New Haven" south=Trenton`,
+			expected: &cityRef{Name: "New York", North: "New Haven", South: "Trenton"},
+		},
+		{
+			line:     `São north="S\u00e3o Paulo" south="a \"b\" #c=d"`,
+			expected: &cityRef{Name: "São", North: "São Paulo", South: `a "b" #c=d`},
+		},
+		{
+			line:     `"New "York north=Bar`,
+			expected: &cityRef{Name: "New York", North: "Bar"},
+		},
+		{
+			line:    `"New York north=Bar`,
+			invalid: true,
+		},
+		{
+			line:    `"" north=Bar`,
+			invalid: true,
+		},
+		{
+			line:    `Foo north=""`,
+			invalid: true,
+		},
+		{
+			line:    `Foo north="\q"`,
+			invalid: true,
+		},
 	}
 	for _, test := range tests {
 		actual, err := parseCityRef(test.line)
 		if test.expected != nil {
+			assert.NoError(t, err, test.line)
 			assert.Equal(t, test.expected, actual, test.line)
 		} else if test.invalid {
 			assert.Error(t, err, test.line)
@@ -159,3 +192,130 @@ func TestLargeDump(t *testing.T) { // grow up
 	assert.NoError(t, err)
 	Golden(t, *updateFlag, "testdata/large-dump-map.golden", &actual)
 }
+
+func TestParseComments(t *testing.T) {
+	in := `
+# map of
+# the northeast
+
+Boston north=Bangor # trailing comment
+# between cities
+Bangor west=Albany#no space
+"Salem #1"
+`
+	m, err := ParseMap(strings.NewReader(in), false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Albany", "Bangor", "Boston", "Salem #1"}, m.CityNames())
+	// # in a quoted name is not a comment
+	assert.Equal(t, []int{5, 6, 7}, m.Comments())
+	var out bytes.Buffer
+	assert.NoError(t, m.Write(&out))
+	expected := `# map of
+# the northeast
+
+Albany east=Bangor
+Bangor south=Boston west=Albany
+Boston north=Bangor
+"Salem #1"
+`
+	assert.Equal(t, expected, out.String())
+
+	ref, err := parseCityRef("# just a comment")
+	assert.NoError(t, err)
+	assert.Nil(t, ref)
+}
+
+func TestParseDirectives(t *testing.T) {
+	in := "#!directions north,south,up,down\nBoston north=Bangor up=Heaven\n"
+	m, err := ParseMap(strings.NewReader(in), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Boston", m.Neighbor("Heaven", Down))
+	var out bytes.Buffer
+	assert.NoError(t, m.Write(&out))
+	expected := `#!directions north,south,up,down
+
+Bangor south=Boston
+Boston north=Bangor up=Heaven
+Heaven down=Boston
+`
+	assert.Equal(t, expected, out.String())
+
+	m, err = ParseMap(strings.NewReader("#!strict\nBoston north=Bangor\n"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "", m.Neighbor("Bangor", South))
+	m, err = ParseMap(strings.NewReader("#! strict # one way roads\nBoston north=Bangor\n"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "", m.Neighbor("Bangor", South))
+	assert.EqualError(t, m.WriteOmitImplied(&out), "map is strict, implied roads cannot be left out")
+
+	// directive is added when map does not have one
+	cities := map[string]*city{"Boston": {Name: "Boston"}, "Heaven": {Name: "Heaven"}}
+	assert.NoError(t, cities["Boston"].addNeighborBidiectional(Up, cities["Heaven"]))
+	out.Reset()
+	assert.NoError(t, dump(&out, cities))
+	assert.Equal(t, "#!directions north,south,east,west,up,down\n\nBoston up=Heaven\nHeaven down=Boston\n", out.String())
+
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{
+			in:       "Boston up=Heaven\n",
+			expected: "parse error, 'up' is not a recognized direction, it can be declared with #!directions",
+		},
+		{
+			in:       "#!directions north,south\nBoston east=Albany\n",
+			expected: "parse error, 'east' is not a recognized direction, it can be declared with #!directions",
+		},
+		{
+			in:       "#!directions up\nBoston up=Heaven\n",
+			expected: "parse error, #!directions has up without down for roads back, use #!strict if roads only go one way",
+		},
+		{
+			in:       "#!directions north,sideways\n",
+			expected: "parse error, 'sideways' in #!directions is not a recognized direction",
+		},
+		{
+			in:       "#!strict please\n",
+			expected: "parse error, #!strict takes no value, got 'please'",
+		},
+		{
+			in:       "#!bogus\n",
+			expected: "parse error, unrecognized directive '#!bogus'",
+		},
+		{
+			in:       "Boston\n#!strict\n",
+			expected: "parse error, directive '#!strict' must come before first city",
+		},
+	}
+	for _, test := range tests {
+		_, err := ParseMap(strings.NewReader(test.in), false)
+		assert.EqualError(t, err, test.expected, test.in)
+	}
+	_, err = ParseMap(strings.NewReader("#!strict\n#!directions up\nBoston up=Heaven\n"), false)
+	assert.NoError(t, err)
+}
+
+func TestQuotedNames(t *testing.T) {
+	m := NewMap()
+	assert.NoError(t, m.AddRoad("New York", North, "São Paulo"))
+	assert.NoError(t, m.AddRoad("New York", South, `Say "Hi"`))
+	assert.NoError(t, m.AddRoad("New York", East, "A=B#C"))
+	assert.NoError(t, m.AddRoad("New York", West, "Tab\tBell\a"))
+	m.AddCity("Zürich")
+	var out bytes.Buffer
+	assert.NoError(t, m.Write(&out))
+	expected := `"A=B#C" west="New York"
+"New York" north="São Paulo" south="Say \"Hi\"" east="A=B#C" west="Tab\tBell\a"
+"Say \"Hi\"" north="New York"
+"São Paulo" south="New York"
+"Tab\tBell\a" east="New York"
+Zürich
+`
+	assert.Equal(t, expected, out.String())
+	again, err := ParseMap(&out, true)
+	assert.NoError(t, err)
+	assert.Equal(t, m.CityNames(), again.CityNames())
+	assert.Equal(t, "São Paulo", again.Neighbor("New York", North))
+	assert.Equal(t, "Tab\tBell\a", again.Neighbor("New York", West))
+}
@@ -0,0 +1,99 @@
+package aliens
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Random is where an invasion gets its random numbers to decide where aliens
+// land and which roads they take.  The same numbers always give the same
+// invasion.
+type Random interface {
+	// Intn is a number from 0 up to but not including n
+	Intn(n int) int
+}
+
+// PCG is the default Random. It is the PCG-XSH-RR generator with 64 bits of
+// state and 32 bits of output from https://www.pcg-random.org. Unlike
+// math/rand, the numbers it gives for a seed are documented and will not
+// change between Go releases.
+type PCG struct {
+	state     uint64
+	increment uint64
+}
+
+// one of the independent sequences PCG can give for the same seed, same one
+// used in the demo of the reference implementation
+const pcgSequence = 54
+
+// NewPCG seeds PCG the same way as pcg32_srandom_r in reference
+// implementation
+func NewPCG(seed int64) *PCG {
+	p := &PCG{increment: pcgSequence<<1 | 1}
+	p.Uint32()
+	p.state += uint64(seed)
+	p.Uint32()
+	return p
+}
+
+// Uint32 is next number in sequence
+func (p *PCG) Uint32() uint32 {
+	old := p.state
+	p.state = old*6364136223846793005 + p.increment
+	xorShifted := uint32(((old >> 18) ^ old) >> 27)
+	rotate := uint32(old >> 59)
+	return xorShifted>>rotate | xorShifted<<((-rotate)&31)
+}
+
+// Intn uses same method as pcg32_boundedrand_r in reference implementation
+// to avoid favoring smaller numbers
+func (p *PCG) Intn(n int) int {
+	if n <= 0 || n > math.MaxUint32 {
+		panic(fmt.Sprintf("invalid argument to Intn %d", n))
+	}
+	bound := uint32(n)
+	threshold := -bound % bound
+	for {
+		if r := p.Uint32(); r >= threshold {
+			return int(r % bound)
+		}
+	}
+}
+
+// MarshalBinary saves state so sequence can be continued later
+func (p *PCG) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data, p.state)
+	binary.BigEndian.PutUint64(data[8:], p.increment)
+	return data, nil
+}
+
+// UnmarshalBinary restores state saved by MarshalBinary
+func (p *PCG) UnmarshalBinary(data []byte) error {
+	if len(data) != 16 {
+		return fmt.Errorf("PCG state is %d bytes, expected 16", len(data))
+	}
+	p.state = binary.BigEndian.Uint64(data)
+	p.increment = binary.BigEndian.Uint64(data[8:])
+	return nil
+}
+
+// ScriptedRandom gives numbers in the order they are listed so tests can
+// control exactly where aliens land and which roads they take. It panics when
+// it runs out of numbers or a number is not less than n.
+type ScriptedRandom struct {
+	Numbers []int
+}
+
+func (s *ScriptedRandom) Intn(n int) int {
+	if len(s.Numbers) == 0 {
+		panic("scripted random numbers ran out")
+	}
+	next := s.Numbers[0]
+	if next < 0 || next >= n {
+		panic(fmt.Sprintf("scripted random number %d is not from 0 to %d", next, n-1))
+	}
+	s.Numbers = s.Numbers[1:]
+	return next
+}
@@ -0,0 +1,69 @@
+package aliens
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPCG(t *testing.T) {
+	// first numbers from pcg32-demo in reference implementation which seeds
+	// with 42 and sequence 54
+	p := NewPCG(42)
+	for _, expected := range []uint32{0xa15c02b7, 0x7b47f409, 0xba1d3330, 0x83d2f293, 0xbfa4784b, 0xcbed606e} {
+		assert.Equal(t, expected, p.Uint32())
+	}
+
+	// continues same sequence after save and restore
+	state, err := p.MarshalBinary()
+	assert.NoError(t, err)
+	expected := p.Intn(1000)
+	restored := &PCG{}
+	assert.NoError(t, restored.UnmarshalBinary(state))
+	assert.Equal(t, expected, restored.Intn(1000))
+	assert.Error(t, restored.UnmarshalBinary(state[:8]))
+
+	counts := make([]int, 3)
+	for i := 0; i < 3000; i++ {
+		counts[p.Intn(3)]++
+	}
+	for _, count := range counts {
+		assert.InDelta(t, 1000, count, 100)
+	}
+	assert.Panics(t, func() { p.Intn(0) })
+}
+
+func TestScriptedRandom(t *testing.T) {
+	// both aliens land in second city, Boston, and destroy it
+	result, err := Invade(Options{
+		AlienNames:     []string{"x", "y"},
+		InvasionRounds: 10,
+		CityMapInput:   strings.NewReader("Boston north=Bangor\nBangor north=Trenton\nTrenton north=Boston\n"),
+		StrictMapParse: true,
+		Random:         &ScriptedRandom{Numbers: []int{1, 1}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []FallenCity{{City: "Boston", Aliens: [2]string{"y", "x"}}}, result.Fallen)
+	assert.Equal(t, EndedNoAliens, result.Ended)
+
+	// x lands in Bangor and y in Trenton, one road out of each city means
+	// any number picks same road
+	result, err = Invade(Options{
+		AlienNames:     []string{"x", "y"},
+		InvasionRounds: 1,
+		CityMapInput:   strings.NewReader("Boston north=Bangor\nBangor north=Trenton\nTrenton north=Boston\n"),
+		StrictMapParse: true,
+		Random:         &ScriptedRandom{Numbers: []int{0, 2, 3, 0}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(result.Fallen))
+	assert.Equal(t, 1, result.Rounds)
+
+	assert.PanicsWithValue(t, "scripted random numbers ran out", func() {
+		(&ScriptedRandom{}).Intn(4)
+	})
+	assert.PanicsWithValue(t, "scripted random number 4 is not from 0 to 3", func() {
+		(&ScriptedRandom{Numbers: []int{4}}).Intn(4)
+	})
+}
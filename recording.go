@@ -0,0 +1,74 @@
+package aliens
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// version of recording format and of the invasion rules it was recorded
+// with.  Recordings from other versions may not replay the same invasion.
+//   2 - random numbers from PCG instead of math/rand
+const recordingVersion = 2
+
+// Recording has everything needed to replay an invasion exactly as it
+// originally happened
+type Recording struct {
+	Version        int    `json:"version"`
+	Map            string `json:"map"`
+	Strict         bool   `json:"strict"`
+	Seed           int64  `json:"seed"`
+	NumberAliens   int    `json:"numberAliens"`
+	InvasionRounds int    `json:"invasionRounds"`
+	MaxRounds      int    `json:"maxRounds,omitempty"`
+
+	AlienNames []string `json:"alienNames,omitempty"`
+}
+
+// NewRecording captures options of an invasion. Map text is required because
+// the CityMapInput reader cannot be read again.
+func NewRecording(options Options, cityMap string) *Recording {
+	return &Recording{
+		Version:        recordingVersion,
+		Map:            cityMap,
+		Strict:         options.StrictMapParse,
+		Seed:           options.Seed,
+		NumberAliens:   options.NumberAliens,
+		InvasionRounds: options.InvasionRounds,
+		MaxRounds:      options.MaxRounds,
+		AlienNames:     options.AlienNames,
+	}
+}
+
+// ReadRecording reads recording written by Recording.Write
+func ReadRecording(r io.Reader) (*Recording, error) {
+	var rec Recording
+	if err := json.NewDecoder(r).Decode(&rec); err != nil {
+		return nil, err
+	}
+	if rec.Version != recordingVersion {
+		return nil, fmt.Errorf("recording version %d is not supported, expected version %d", rec.Version, recordingVersion)
+	}
+	return &rec, nil
+}
+
+// Write recording as JSON document
+func (rec *Recording) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rec)
+}
+
+// Options to replay invasion.  Caller can still direct output.
+func (rec *Recording) Options() Options {
+	return Options{
+		NumberAliens:   rec.NumberAliens,
+		InvasionRounds: rec.InvasionRounds,
+		MaxRounds:      rec.MaxRounds,
+		Seed:           rec.Seed,
+		CityMapInput:   strings.NewReader(rec.Map),
+		StrictMapParse: rec.Strict,
+		AlienNames:     rec.AlienNames,
+	}
+}
@@ -0,0 +1,41 @@
+package aliens
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecording(t *testing.T) {
+	cityMap, err := ioutil.ReadFile("testdata/small-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := Options{
+		Seed:           10,
+		NumberAliens:   10,
+		InvasionRounds: 10,
+		CityMapInput:   bytes.NewReader(cityMap),
+	}
+	original, err := Invade(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	assert.NoError(t, NewRecording(options, string(cityMap)).Write(&buf))
+	rec, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := Invade(rec.Options())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, original, replayed)
+
+	_, err = ReadRecording(strings.NewReader(`{"version":99}`))
+	assert.Error(t, err)
+}
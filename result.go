@@ -0,0 +1,114 @@
+package aliens
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Result of an invasion. All lists of cities are in city name sorted order
+// unless noted otherwise.
+type Result struct {
+	Seed      int64    `json:"seed"`
+	Rounds    int      `json:"rounds"` // not including initial landing
+	Remaining []string `json:"remaining"`
+
+	// Ended is how invasion ended, one of the Ended constants. EndedMaxRounds
+	// means invasion was stopped short of the rounds asked for
+	Ended string `json:"ended"`
+
+	// Fallen are cities in the order they were destroyed
+	Fallen []FallenCity `json:"fallen"`
+
+	AliensLeft    int `json:"aliensLeft"`    // still roaming at end of invasion
+	AliensTrapped int `json:"aliensTrapped"` // in cities without roads out
+
+	Fragmentation *Fragmentation `json:"fragmentation"`
+
+	// Itineraries of aliens when Options.TrackItineraries is set
+	Itineraries Itineraries `json:"itineraries,omitempty"`
+
+	// CityHistories of every city when Options.TrackCityHistory is set
+	CityHistories CityHistories `json:"cityHistories,omitempty"`
+
+	// Interrupted is the reason invasion was stopped before it was over, empty
+	// when invasion ran to completion
+	Interrupted string `json:"interrupted,omitempty"`
+}
+
+// AliensKilled is two for every fallen city
+func (r *Result) AliensKilled() int {
+	return 2 * len(r.Fallen)
+}
+
+// FallenCity is a city destroyed by two aliens
+type FallenCity struct {
+	City   string    `json:"city"`
+	Aliens [2]string `json:"aliens"`
+}
+
+// String is formatted according to spec, see README.md
+func (f FallenCity) String() string {
+	return fmt.Sprintf("%s has been destroyed by alien %s and alien %s!", f.City, f.Aliens[0], f.Aliens[1])
+}
+
+// Fragmentation describes how badly the remaining cities were cut off from
+// each other by the invasion
+type Fragmentation struct {
+	// Components are groups of remaining cities still connected by roads in
+	// either direction, largest group first
+	Components [][]string `json:"components"`
+
+	// Isolated are remaining cities that lost all their roads
+	Isolated []string `json:"isolated"`
+
+	// Severed are the roads removed as cities were destroyed, in the order
+	// they were removed
+	Severed []Road `json:"severed"`
+}
+
+func fragmentation(remaining map[string]*city, severed []Road) *Fragmentation {
+	f := &Fragmentation{
+		Severed: severed,
+	}
+	names, _, neighbors := roadIndex(remaining)
+	f.Components = components(names, neighbors)
+	lostRoads := make(map[string]bool)
+	for _, road := range severed {
+		lostRoads[road.From] = true
+		lostRoads[road.To] = true
+	}
+	for i, name := range names {
+		if len(neighbors[i]) == 0 && lostRoads[name] {
+			f.Isolated = append(f.Isolated, name)
+		}
+	}
+	return f
+}
+
+// Write fragmentation as human readable report
+func (f *Fragmentation) Write(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "components: %d\n", len(f.Components))
+	for _, group := range f.Components {
+		fmt.Fprintf(&b, "  %s\n", strings.Join(group, " "))
+	}
+	fmt.Fprintf(&b, "isolated: %d\n", len(f.Isolated))
+	for _, name := range f.Isolated {
+		fmt.Fprintf(&b, "  %s\n", name)
+	}
+	fmt.Fprintf(&b, "severed: %d\n", len(f.Severed))
+	for _, road := range f.Severed {
+		fmt.Fprintf(&b, "  %s %s=%s\n", road.From, road.Direction, road.To)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes fragmentation as JSON document
+func (f *Fragmentation) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
@@ -0,0 +1,51 @@
+package aliens
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFragmentation(t *testing.T) {
+	cities, err := parse(strings.NewReader(`
+Boston north=Bangor south=NewYork west=Albany
+NewYork west=Columbus
+`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	severed := cities["Boston"].destroy(cities, alien("0"), alien("1"))
+	delete(cities, "Boston")
+	f := fragmentation(cities, severed)
+	assert.Equal(t, [][]string{{"Columbus", "NewYork"}, {"Albany"}, {"Bangor"}}, f.Components)
+	assert.Equal(t, []string{"Albany", "Bangor"}, f.Isolated)
+	assert.Equal(t, 6, len(f.Severed))
+}
+
+func TestFragmentationReport(t *testing.T) {
+	in, err := os.Open("testdata/small-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	var buf bytes.Buffer
+	result, err := Invade(Options{
+		Seed:                4,
+		RemaingCitiesOutput: &buf,
+		NumberAliens:        10,
+		InvasionRounds:      10,
+		CityMapInput:        in,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"Albany", "Columbus"}, result.Remaining)
+	buf.Reset()
+	assert.NoError(t, result.Fragmentation.Write(&buf))
+	Golden(t, *updateFlag, "testdata/aliens-trapped-fragmentation.golden", &buf)
+	assert.NoError(t, result.Fragmentation.WriteJSON(&buf))
+	Golden(t, *updateFlag, "testdata/aliens-trapped-fragmentation.json", &buf)
+}
@@ -0,0 +1,102 @@
+package aliens
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// RoundMap is what is left of the map and where aliens are at the end of a
+// round
+type RoundMap struct {
+	Round int `json:"round"` // zero is after aliens land
+
+	// remaining cities in same form as Map.WriteJSON
+	Cities map[string]map[string]string `json:"cities"`
+
+	// aliens still roaming and aliens trapped by city they are in
+	Aliens  map[string]string `json:"aliens"`
+	Trapped map[string]string `json:"trapped"`
+
+	// destroyed cities in the order they fell
+	Destroyed []string `json:"destroyed"`
+}
+
+func (sim *Invasion) roundMap() *RoundMap {
+	m := &RoundMap{
+		Round:     sim.roundsCompleted,
+		Cities:    make(map[string]map[string]string),
+		Aliens:    make(map[string]string, len(sim.invaded)),
+		Trapped:   make(map[string]string, len(sim.trapped)),
+		Destroyed: make([]string, 0, len(sim.fallen)),
+	}
+	for name, c := range sim.cities {
+		if _, destroyed := sim.destroyed[name]; destroyed {
+			continue
+		}
+		m.Cities[name] = c.roadLabels()
+	}
+	for c, a := range sim.invaded {
+		m.Aliens[c.Name] = string(a)
+	}
+	for c, a := range sim.trapped {
+		m.Trapped[c.Name] = string(a)
+	}
+	for _, fallen := range sim.fallen {
+		m.Destroyed = append(m.Destroyed, fallen.City)
+	}
+	return m
+}
+
+// Write round map in same format as ParseMap reads with round, destroyed
+// cities and the alien in each city as comments.
+// Example:
+//   # round 3
+//   # destroyed NewYork Bangor
+//   Albany east=Boston # alien 8
+//   Boston west=Albany # alien 2 trapped
+func (m *RoundMap) Write(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# round %d\n", m.Round)
+	if len(m.Destroyed) > 0 {
+		destroyed := make([]string, len(m.Destroyed))
+		for i, name := range m.Destroyed {
+			destroyed[i] = quoteName(name)
+		}
+		fmt.Fprintf(&b, "# destroyed %s\n", strings.Join(destroyed, " "))
+	}
+	names := make([]string, 0, len(m.Cities))
+	for name := range m.Cities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(quoteName(name))
+		for _, label := range directionLabels {
+			if neighbor, exists := m.Cities[name][label]; exists {
+				fmt.Fprintf(&b, " %s=%s", label, quoteName(neighbor))
+			}
+		}
+		var occupants []string
+		if a, exists := m.Aliens[name]; exists {
+			occupants = append(occupants, "alien "+a)
+		}
+		if a, exists := m.Trapped[name]; exists {
+			occupants = append(occupants, "alien "+a+" trapped")
+		}
+		if len(occupants) > 0 {
+			fmt.Fprintf(&b, " # %s", strings.Join(occupants, ", "))
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes round map as a single line JSON document so round maps can
+// be written one after another to the same stream
+func (m *RoundMap) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(m)
+}
@@ -0,0 +1,76 @@
+package aliens
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundMaps(t *testing.T) {
+	in, err := os.Open("testdata/small-map.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	var buf bytes.Buffer
+	_, err = Invade(Options{
+		Seed:           4,
+		NumberAliens:   10,
+		InvasionRounds: 10,
+		CityMapInput:   in,
+		RoundMaps: func(m *RoundMap) error {
+			return m.Write(&buf)
+		},
+	})
+	assert.NoError(t, err)
+	Golden(t, *updateFlag, "testdata/round-maps.golden", &buf)
+
+	var maps []*RoundMap
+	_, err = Invade(Options{
+		Seed:           20,
+		NumberAliens:   10,
+		InvasionRounds: 10,
+		CityMapInput:   strings.NewReader("Boston north=Bangor south=NewYork west=Albany\nNewYork south=Trenton west=Columbus\n"),
+		RoundMaps: func(m *RoundMap) error {
+			maps = append(maps, m)
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	buf.Reset()
+	assert.NoError(t, maps[len(maps)-1].WriteJSON(&buf))
+	Golden(t, *updateFlag, "testdata/round-maps.json", &buf)
+}
+
+func TestRoundMapsEvery(t *testing.T) {
+	cityMap := &Map{cities: generateCityMap(5)}
+	tests := []struct {
+		every    int
+		rounds   int
+		expected []int
+	}{
+		{every: 0, rounds: 3, expected: []int{0, 1, 2, 3}},
+		{every: 4, rounds: 10, expected: []int{0, 4, 8, 10}},
+		{every: 5, rounds: 10, expected: []int{0, 5, 10}},
+	}
+	for _, test := range tests {
+		var actual []int
+		_, err := Invade(Options{
+			Seed:           1,
+			NumberAliens:   10,
+			InvasionRounds: test.rounds,
+			CityMap:        cityMap,
+			RoundMapsEvery: test.every,
+			RoundMaps: func(m *RoundMap) error {
+				actual = append(actual, m.Round)
+				assert.Equal(t, cityMap.Len()-len(m.Destroyed), len(m.Cities))
+				return nil
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, actual, "every %d", test.every)
+	}
+}
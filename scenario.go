@@ -0,0 +1,126 @@
+package aliens
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Defaults for values a scenario leaves out, same as run command line
+const (
+	DefaultNumberAliens   = 10
+	DefaultInvasionRounds = 10000
+)
+
+// Scenario is everything about an invasion kept in a YAML file so it doesn't
+// have to be remembered as a long list of command line flags.
+// Example:
+//   map: small-map.txt
+//   seed: 10
+//   aliens:
+//     count: 10
+//     strategy: hunt
+//   rules:
+//     rounds: 100
+//     strict: true
+//   output:
+//     remaining: remaining.txt
+type Scenario struct {
+	// city map file, relative to scenario file
+	Map  string `yaml:"map"`
+	Seed int64  `yaml:"seed"`
+
+	Aliens struct {
+		Count int      `yaml:"count"`
+		Names []string `yaml:"names"` // optional roster, overrides count
+
+		// how aliens pick which road to take, see Options.Strategy
+		Strategy string `yaml:"strategy"`
+	} `yaml:"aliens"`
+
+	Rules struct {
+		Rounds    int  `yaml:"rounds"` // -1 until invasion is over
+		MaxRounds int  `yaml:"maxRounds"`
+		Strict    bool `yaml:"strict"`
+	} `yaml:"rules"`
+
+	// output files, relative to scenario file
+	Output struct {
+		Remaining    string `yaml:"remaining"`
+		Report       string `yaml:"report"`
+		ReportFormat string `yaml:"reportFormat"`
+		Record       string `yaml:"record"`
+	} `yaml:"output"`
+}
+
+// LoadScenario reads scenario file and resolves the files it refers to
+// relative to the directory of the scenario file
+func LoadScenario(fname string) (*Scenario, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := ReadScenario(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	dir := filepath.Dir(fname)
+	for _, path := range []*string{&s.Map, &s.Output.Remaining, &s.Output.Report, &s.Output.Record} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+	return s, nil
+}
+
+// ReadScenario reads scenario as is, files it refers to are not resolved
+func ReadScenario(r io.Reader) (*Scenario, error) {
+	var s Scenario
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+	if err := checkStrategy(s.Aliens.Strategy); err != nil {
+		return nil, err
+	}
+	if s.Output.ReportFormat != "" && s.Output.ReportFormat != "text" && s.Output.ReportFormat != "json" {
+		return nil, fmt.Errorf("unrecognized report format '%s'", s.Output.ReportFormat)
+	}
+	return &s, nil
+}
+
+// Options reads the city map, following any include lines in it, and builds
+// options to run scenario. Aliens and rounds left out of scenario get the
+// defaults. Output files are left to the caller.
+func (s *Scenario) Options() (Options, error) {
+	if s.Map == "" {
+		return Options{}, fmt.Errorf("scenario has no map")
+	}
+	// errors already say which map file they are from
+	m, err := ParseMapFile(s.Map, s.Rules.Strict)
+	if err != nil {
+		return Options{}, err
+	}
+	numAliens, rounds := s.Aliens.Count, s.Rules.Rounds
+	if numAliens == 0 {
+		numAliens = DefaultNumberAliens
+	}
+	if rounds == 0 {
+		rounds = DefaultInvasionRounds
+	}
+	return Options{
+		NumberAliens:   numAliens,
+		AlienNames:     s.Aliens.Names,
+		Strategy:       s.Aliens.Strategy,
+		InvasionRounds: rounds,
+		MaxRounds:      s.Rules.MaxRounds,
+		Seed:           s.Seed,
+		StrictMapParse: s.Rules.Strict,
+		CityMap:        m,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package aliens
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScenario(t *testing.T) {
+	s, err := LoadScenario("testdata/small-scenario.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, filepath.Join("testdata", "small-map.txt"), s.Map)
+	options, err := s.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(10), options.Seed)
+	assert.Equal(t, 10, options.InvasionRounds)
+	assert.Equal(t, []string{"Zork", "Blorp", "Gleep", "Xanthu"}, options.AlienNames)
+	assert.Equal(t, StrategyRandom, options.Strategy)
+	assert.Equal(t, 6, options.CityMap.Len())
+
+	var buf bytes.Buffer
+	options.RemaingCitiesOutput = &buf
+	options.FallenCitiesOutput = &buf
+	options.Logger = testLogger(&buf)
+	_, err = Invade(options)
+	assert.NoError(t, err)
+	Golden(t, *updateFlag, "testdata/small-scenario.golden", &buf)
+}
+
+func TestScenarioDefaults(t *testing.T) {
+	s, err := ReadScenario(strings.NewReader("map: testdata/small-map.txt\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	options, err := s.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, DefaultNumberAliens, options.NumberAliens)
+	assert.Equal(t, DefaultInvasionRounds, options.InvasionRounds)
+}
+
+func TestScenarioIncludes(t *testing.T) {
+	s, err := LoadScenario("testdata/include-scenario.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	options, err := s.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, options.CityMap.Len())
+	_, err = Invade(options)
+	assert.NoError(t, err)
+}
+
+func TestBadScenario(t *testing.T) {
+	_, err := ReadScenario(strings.NewReader("aliens:\n  strategy: clever\n"))
+	assert.Error(t, err)
+	_, err = ReadScenario(strings.NewReader("bogus: 1\n"))
+	assert.Error(t, err)
+	_, err = LoadScenario("testdata/no-such-scenario.yaml")
+	assert.Error(t, err)
+	_, err = Invade(Options{AlienNames: []string{"Zork", "Zork"}, CityMap: NewMap()})
+	assert.Error(t, err)
+}
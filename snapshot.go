@@ -0,0 +1,238 @@
+package aliens
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// version of snapshot format.  Snapshots from other versions cannot be
+// resumed.
+//
+//	2 - random numbers from PCG instead of math/rand
+const snapshotVersion = 2
+
+// Snapshot is the full state of an invasion in progress so it can be paused
+// and resumed later, possibly on another machine, with the same outcome as
+// if it was never paused.
+type Snapshot struct {
+	Version int `json:"version"`
+
+	// map as it is now, roads severed by destroyed cities are gone
+	Map       string   `json:"map"`
+	Destroyed []string `json:"destroyed"`
+
+	// seed invasion started with and where PCG random numbers are now
+	Seed        int64  `json:"seed"`
+	RandomState []byte `json:"randomState"`
+
+	// directions aliens pick from when moving, zero for compass directions
+	MoveDirections int `json:"moveDirections,omitempty"`
+
+	// how aliens pick which road to take, empty for StrategyRandom
+	Strategy string `json:"strategy,omitempty"`
+
+	InvasionRounds  int `json:"invasionRounds"`
+	MaxRounds       int `json:"maxRounds,omitempty"`
+	RoundsCompleted int `json:"roundsCompleted"`
+
+	// all aliens in the order they land and how many have landed
+	Aliens []string `json:"aliens"`
+	Landed int      `json:"landed"`
+
+	// aliens still roaming and aliens trapped, in city name sorted order
+	Invaded []AlienPosition `json:"invaded"`
+	Trapped []AlienPosition `json:"trapped"`
+
+	Fallen  []FallenCity `json:"fallen"`
+	Severed []Road       `json:"severed"`
+
+	// itineraries so far when they are tracked
+	Itineraries Itineraries `json:"itineraries,omitempty"`
+
+	// city histories so far when they are tracked
+	CityHistories CityHistories `json:"cityHistories,omitempty"`
+
+	// alien positions seen while looking for aliens going in circles
+	Seen []string `json:"seen,omitempty"`
+
+	// how invasion ended, empty if it is not over
+	Ended string `json:"ended,omitempty"`
+}
+
+// AlienPosition is the city an alien is in
+type AlienPosition struct {
+	Alien string `json:"alien"`
+	City  string `json:"city"`
+}
+
+// Snapshot of invasion. Invasion should not be running, take snapshot after
+// Run returns because it was interrupted. Only invasions using PCG random
+// numbers can be saved.
+func (sim *Invasion) Snapshot() (*Snapshot, error) {
+	pcg, isPCG := sim.rnd.(*PCG)
+	if !isPCG {
+		return nil, fmt.Errorf("cannot save state of %T random numbers", sim.rnd)
+	}
+	randomState, err := pcg.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var m strings.Builder
+	if err := dump(&m, sim.cities); err != nil {
+		// cannot fail writing to memory
+		panic(err)
+	}
+	s := &Snapshot{
+		Version:         snapshotVersion,
+		Map:             m.String(),
+		Destroyed:       cityNames(sim.destroyed),
+		Seed:            sim.seed,
+		RandomState:     randomState,
+		InvasionRounds:  sim.rounds,
+		MaxRounds:       sim.maxRounds,
+		Strategy:        sim.strategy,
+		RoundsCompleted: sim.roundsCompleted,
+		Landed:          sim.landed,
+		Invaded:         alienPositionList(sim.invaded),
+		Trapped:         alienPositionList(sim.trapped),
+		Fallen:          sim.fallen,
+		Severed:         sim.severed,
+		Itineraries:     sim.itineraryList(false),
+		CityHistories:   sim.cityHistoryList(),
+	}
+	if sim.moveDirections != len(compassLabels) {
+		s.MoveDirections = sim.moveDirections
+	}
+	for _, a := range sim.aliens {
+		s.Aliens = append(s.Aliens, string(a))
+	}
+	for positions := range sim.seen {
+		s.Seen = append(s.Seen, positions)
+	}
+	sort.Strings(s.Seen)
+	if sim.ended != EndedInterrupted {
+		s.Ended = sim.ended
+	}
+	return s, nil
+}
+
+func alienPositionList(cities map[*city]alien) []AlienPosition {
+	positions := make([]AlienPosition, 0, len(cities))
+	for c, a := range cities {
+		positions = append(positions, AlienPosition{Alien: string(a), City: c.Name})
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i].City < positions[j].City
+	})
+	return positions
+}
+
+// ResumeInvasion picks up invasion where snapshot left off. Only outputs,
+// logger and time budget are taken from options, everything else comes from
+// snapshot.
+func ResumeInvasion(s *Snapshot, options Options) (*Invasion, error) {
+	cities, err := parse(strings.NewReader(s.Map), true)
+	if err != nil {
+		return nil, err
+	}
+	sim := &Invasion{
+		cities:          cities,
+		rounds:          s.InvasionRounds,
+		maxRounds:       s.MaxRounds,
+		strategy:        s.Strategy,
+		roundsCompleted: s.RoundsCompleted,
+		landed:          s.Landed,
+		fallen:          s.Fallen,
+		severed:         s.Severed,
+		ended:           s.Ended,
+		seed:            s.Seed,
+		destroyed:       make(map[string]*city),
+	}
+	if err := checkStrategy(s.Strategy); err != nil {
+		return nil, err
+	}
+	sim.outputs(options)
+	sim.moveDirections = s.MoveDirections
+	if sim.moveDirections == 0 {
+		sim.moveDirections = len(compassLabels)
+	}
+	pcg := &PCG{}
+	if err = pcg.UnmarshalBinary(s.RandomState); err != nil {
+		return nil, err
+	}
+	sim.rnd = pcg
+	if sim.aliens, err = namedAliens(s.Aliens); err != nil {
+		return nil, err
+	}
+	if s.Itineraries != nil {
+		sim.itineraries = make(map[alien]*Itinerary, len(s.Itineraries))
+		for i := range s.Itineraries {
+			sim.itineraries[alien(s.Itineraries[i].Alien)] = &s.Itineraries[i]
+		}
+	}
+	if s.CityHistories != nil {
+		sim.cityHistories = make(map[string]*CityHistory, len(s.CityHistories))
+		for i := range s.CityHistories {
+			sim.cityHistories[s.CityHistories[i].City] = &s.CityHistories[i]
+		}
+	}
+	sim.lastMapped = -1
+	if sim.landed == len(sim.aliens) {
+		// map of last round was given before invasion was paused
+		sim.lastMapped = sim.roundsCompleted
+	}
+	for _, name := range s.Destroyed {
+		c, exists := cities[name]
+		if !exists {
+			return nil, fmt.Errorf("destroyed city %s is not on map", name)
+		}
+		sim.destroyed[name] = c
+	}
+	if sim.invaded, err = alienPositionMap(cities, s.Invaded); err != nil {
+		return nil, err
+	}
+	if sim.trapped, err = alienPositionMap(cities, s.Trapped); err != nil {
+		return nil, err
+	}
+	if len(s.Seen) > 0 {
+		sim.seen = make(map[string]bool, len(s.Seen))
+		for _, positions := range s.Seen {
+			sim.seen[positions] = true
+		}
+	}
+	return sim, nil
+}
+
+func alienPositionMap(cities map[string]*city, positions []AlienPosition) (map[*city]alien, error) {
+	m := make(map[*city]alien, len(positions))
+	for _, p := range positions {
+		c, exists := cities[p.City]
+		if !exists {
+			return nil, fmt.Errorf("alien %s is in city %s that is not on map", p.Alien, p.City)
+		}
+		m[c] = alien(p.Alien)
+	}
+	return m, nil
+}
+
+// ReadSnapshot reads snapshot written by Snapshot.Write
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("snapshot version %d is not supported, expected version %d", s.Version, snapshotVersion)
+	}
+	return &s, nil
+}
+
+// Write snapshot as JSON document
+func (s *Snapshot) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
@@ -0,0 +1,148 @@
+package aliens
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pauseWriter cancels context after a number of writes
+type pauseWriter struct {
+	out    *bytes.Buffer
+	after  int
+	cancel context.CancelFunc
+}
+
+func (w *pauseWriter) Write(p []byte) (int, error) {
+	w.after--
+	if w.after == 0 {
+		w.cancel()
+	}
+	return w.out.Write(p)
+}
+
+func TestSnapshotResume(t *testing.T) {
+	cityMap := &Map{cities: generateCityMap(5)}
+	ring := "Boston north=Bangor\nBangor north=Trenton\nTrenton north=Boston\n"
+	ringMap, err := ParseMap(strings.NewReader(ring), true)
+	assert.NoError(t, err)
+	tower := "#!directions north,south,east,west,up,down\nA north=B up=C\nB up=D\nC north=D\nD up=E\n"
+	towerMap, err := ParseMap(strings.NewReader(tower), false)
+	assert.NoError(t, err)
+	tests := []struct {
+		name    string
+		options Options
+	}{
+		{
+			name:    "landing",
+			options: Options{Seed: 1, NumberAliens: 300, InvasionRounds: 100, CityMap: cityMap},
+		},
+		{
+			name:    "rounds",
+			options: Options{Seed: 2, NumberAliens: 100, InvasionRounds: 100, CityMap: cityMap},
+		},
+		{
+			name:    "until over",
+			options: Options{Seed: 3, NumberAliens: 100, InvasionRounds: UntilOver, MaxRounds: 200, CityMap: cityMap},
+		},
+		{
+			name:    "cycle",
+			options: Options{Seed: 2, AlienNames: []string{"x", "y"}, InvasionRounds: UntilOver, CityMap: ringMap},
+		},
+		{
+			name:    "hunt",
+			options: Options{Seed: 4, NumberAliens: 100, InvasionRounds: 100, Strategy: StrategyHunt, CityMap: cityMap},
+		},
+		{
+			name:    "up and down",
+			options: Options{Seed: 5, NumberAliens: 4, InvasionRounds: 100, CityMap: towerMap},
+		},
+	}
+	for _, test := range tests {
+		var expectedOut, expectedMaps bytes.Buffer
+		test.options.FallenCitiesOutput = &expectedOut
+		test.options.RoundMaps = roundMapWriter(&expectedMaps)
+		test.options.RoundMapsEvery = 7
+		test.options.TrackItineraries = true
+		test.options.TrackCityHistory = true
+		expected, err := Invade(test.options)
+		assert.NoError(t, err)
+
+		// pause after about every tenth fallen city
+		for pause := 1; pause <= len(expected.Fallen)+1; pause += 1 + len(expected.Fallen)/10 {
+			var out, maps bytes.Buffer
+			ctx, cancel := context.WithCancel(context.Background())
+			test.options.FallenCitiesOutput = &pauseWriter{out: &out, after: pause, cancel: cancel}
+			test.options.RoundMaps = roundMapWriter(&maps)
+			invasion, err := NewInvasion(test.options)
+			assert.NoError(t, err)
+			actual, err := invasion.Run(ctx)
+			cancel()
+			if err == nil {
+				// invasion was over before pause
+				assert.Equal(t, jsonString(t, expected), jsonString(t, actual), test.name)
+				continue
+			}
+			assert.ErrorIs(t, err, context.Canceled)
+			var buf bytes.Buffer
+			snapshot, err := invasion.Snapshot()
+			assert.NoError(t, err)
+			assert.NoError(t, snapshot.Write(&buf))
+			snapshot, err = ReadSnapshot(&buf)
+			assert.NoError(t, err)
+			resumed, err := ResumeInvasion(snapshot, Options{
+				FallenCitiesOutput: &out,
+				RoundMaps:          roundMapWriter(&maps),
+				RoundMapsEvery:     test.options.RoundMapsEvery,
+			})
+			assert.NoError(t, err)
+			actual, err = resumed.Run(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, jsonString(t, expected), jsonString(t, actual), "%s paused at %d", test.name, pause)
+			assert.Equal(t, expectedOut.String(), out.String(), "%s paused at %d", test.name, pause)
+			assert.Equal(t, expectedMaps.String(), maps.String(), "%s paused at %d", test.name, pause)
+		}
+	}
+}
+
+func TestSnapshotOver(t *testing.T) {
+	invasion, err := NewInvasion(Options{
+		Seed:           2,
+		AlienNames:     []string{"x", "y"},
+		InvasionRounds: UntilOver,
+		CityMapInput:   strings.NewReader("Boston north=Bangor\nBangor north=Trenton\nTrenton north=Boston\n"),
+		StrictMapParse: true,
+	})
+	assert.NoError(t, err)
+	expected, err := invasion.Run(context.Background())
+	assert.NoError(t, err)
+	snapshot, err := invasion.Snapshot()
+	assert.NoError(t, err)
+	resumed, err := ResumeInvasion(snapshot, Options{})
+	assert.NoError(t, err)
+	actual, err := resumed.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestReadSnapshotVersion(t *testing.T) {
+	_, err := ReadSnapshot(strings.NewReader(`{"version":99}`))
+	assert.EqualError(t, err, "snapshot version 99 is not supported, expected version 2")
+}
+
+func roundMapWriter(w io.Writer) func(*RoundMap) error {
+	return func(m *RoundMap) error {
+		return m.WriteJSON(w)
+	}
+}
+
+func jsonString(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return string(data)
+}
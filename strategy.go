@@ -0,0 +1,46 @@
+package aliens
+
+import "fmt"
+
+// how aliens pick which road to take when they move, see Options.Strategy
+const (
+	StrategyRandom = "random" // any road out
+	StrategyHunt   = "hunt"   // roads to cities another alien is in when there are any
+	StrategyAvoid  = "avoid"  // roads to cities no other alien is in when there are any
+)
+
+// Strategies aliens can use
+var Strategies = []string{StrategyRandom, StrategyHunt, StrategyAvoid}
+
+// checkStrategy allows empty strategy for StrategyRandom
+func checkStrategy(strategy string) error {
+	if strategy == "" {
+		return nil
+	}
+	for _, known := range Strategies {
+		if strategy == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognized strategy '%s'", strategy)
+}
+
+// nextCity picks neighboring city alien moves to according to strategy or
+// nil if there are no roads out. occupied tells if another alien is in a
+// city. When no road suits the strategy, any road is taken.
+func (sim *Invasion) nextCity(c *city, occupied func(*city) bool) *city {
+	if sim.strategy != StrategyHunt && sim.strategy != StrategyAvoid {
+		return sim.nextRandomCity(c)
+	}
+	var suited []*city
+	for direction := 0; direction < sim.moveDirections; direction++ {
+		neighbor := c.neighoringCity(direction)
+		if neighbor != nil && occupied(neighbor) == (sim.strategy == StrategyHunt) {
+			suited = append(suited, neighbor)
+		}
+	}
+	if len(suited) == 0 {
+		return sim.nextRandomCity(c)
+	}
+	return suited[sim.rnd.Intn(len(suited))]
+}
@@ -0,0 +1,46 @@
+package aliens
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrategy(t *testing.T) {
+	cities, err := parse(strings.NewReader("X north=A south=B east=C west=D\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, a := cities["X"], cities["A"]
+	inA := func(c *city) bool { return c == a }
+	nobody := func(c *city) bool { return false }
+	tests := []struct {
+		strategy string
+		occupied func(*city) bool
+		check    func(picked map[*city]bool)
+	}{
+		{StrategyRandom, inA, func(picked map[*city]bool) { assert.Equal(t, 4, len(picked)) }},
+		{StrategyHunt, inA, func(picked map[*city]bool) { assert.Equal(t, map[*city]bool{a: true}, picked) }},
+		{StrategyHunt, nobody, func(picked map[*city]bool) { assert.Equal(t, 4, len(picked)) }},
+		{StrategyAvoid, inA, func(picked map[*city]bool) {
+			assert.Equal(t, 3, len(picked))
+			assert.False(t, picked[a])
+		}},
+	}
+	for _, test := range tests {
+		sim := &Invasion{rnd: NewPCG(1), strategy: test.strategy, moveDirections: len(compassLabels)}
+		picked := make(map[*city]bool)
+		for i := 0; i < 100; i++ {
+			picked[sim.nextCity(x, test.occupied)] = true
+		}
+		test.check(picked)
+	}
+}
+
+func TestBadStrategy(t *testing.T) {
+	_, err := NewInvasion(Options{NumberAliens: 2, Strategy: "clever", CityMapInput: strings.NewReader("A north=B\n")})
+	assert.EqualError(t, err, "unrecognized strategy 'clever'")
+	_, err = ReadScenario(strings.NewReader("map: x.txt\naliens:\n  strategy: clever\n"))
+	assert.EqualError(t, err, "unrecognized strategy 'clever'")
+}